@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gomtp/smtpclient"
+)
+
+// Parse an --attach value of the form path[;name=...][;type=...][;inline].
+func parseAttachFlag(value string) (smtpclient.Attachment, error) {
+	parts := strings.Split(value, ";")
+	attachment := smtpclient.Attachment{Path: strings.TrimSpace(parts[0])}
+	if attachment.Path == "" {
+		return attachment, fmt.Errorf("invalid --attach %q: path is empty", value)
+	}
+	for _, part := range parts[1:] {
+		key, val, hasValue := strings.Cut(strings.TrimSpace(part), "=")
+		switch strings.ToLower(key) {
+		case "name":
+			attachment.Name = val
+		case "type":
+			attachment.ContentType = val
+		case "inline":
+			inline := true
+			if hasValue {
+				var err error
+				if inline, err = strconv.ParseBool(val); err != nil {
+					return attachment, fmt.Errorf("invalid --attach %q: inline must be true or false", value)
+				}
+			}
+			attachment.Inline = inline
+		default:
+			return attachment, fmt.Errorf("invalid --attach %q: unknown option %q; use name, type or inline", value, key)
+		}
+	}
+	return attachment, nil
+}
+
+// Append the --attach flags to the attachments of the config.
+func setAttachFlags(emailConfig *smtpclient.EmailConfig) error {
+	for _, value := range attachList {
+		attachment, err := parseAttachFlag(value)
+		if err != nil {
+			return err
+		}
+		emailConfig.Attachments = append(emailConfig.Attachments, attachment)
+	}
+	return nil
+}
+
+// Print the name, size and type of every attachment.
+func printAttachments(w io.Writer, files []smtpclient.AttachmentFile) {
+	for _, file := range files {
+		if file.Inline {
+			fmt.Fprintf(w, "Embedding %s as cid:%s (%s, %s)\n", file.Path, file.Name, formatSize(file.Size), file.ContentType)
+		} else {
+			fmt.Fprintf(w, "Attaching %s as %s (%s, %s)\n", file.Path, file.Name, formatSize(file.Size), file.ContentType)
+		}
+	}
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
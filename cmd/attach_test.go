@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+
+	"gomtp/smtpclient"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAttachFlag(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected smtpclient.Attachment
+	}{
+		{"report.pdf", smtpclient.Attachment{Path: "report.pdf"}},
+		{"logs/*.log;type=text/plain", smtpclient.Attachment{Path: "logs/*.log", ContentType: "text/plain"}},
+		{"logo.png;name=brand.png;inline", smtpclient.Attachment{Path: "logo.png", Name: "brand.png", Inline: true}},
+		{"logo.png; inline=false", smtpclient.Attachment{Path: "logo.png"}},
+	}
+	for _, tt := range tests {
+		attachment, err := parseAttachFlag(tt.value)
+		assert.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, attachment, tt.value)
+	}
+}
+
+func TestParseAttachFlagErrors(t *testing.T) {
+	for _, value := range []string{"", ";name=x", "a.txt;size=1", "a.txt;inline=maybe"} {
+		_, err := parseAttachFlag(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatSize(512))
+	assert.Equal(t, "1.5 KiB", formatSize(1536))
+	assert.Equal(t, "3.0 MiB", formatSize(3*1024*1024))
+}
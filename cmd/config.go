@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gomtp/smtpclient"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// gomtpConfig is the YAML configuration file: the email config plus the keys only the CLI uses.
+type gomtpConfig struct {
+	smtpclient.EmailConfig `yaml:",inline"`
+	Vars                   map[string]interface{} `yaml:"vars"`
+}
+
+// Return an empty configuration. An omitted key keeps the zero value, e.g. verifyCertificate verifies.
+func newConfig() gomtpConfig {
+	return gomtpConfig{}
+}
+
+// profilesFile is a configuration file with named accounts. Every profile is
+// applied on top of the defaults, so it only lists what differs.
+type profilesFile struct {
+	Defaults yaml.Node            `yaml:"defaults"`
+	Profiles map[string]yaml.Node `yaml:"profiles"`
+}
+
+// profilesConfig decodes a profiles file strictly, to report unknown keys.
+type profilesConfig struct {
+	Defaults gomtpConfig            `yaml:"defaults"`
+	Profiles map[string]gomtpConfig `yaml:"profiles"`
+}
+
+var unknownKeyPattern = regexp.MustCompile(`field (\S+) not found in type \S+`)
+
+// Decode the YAML into v, rejecting the keys v does not have. Every problem is
+// reported with its line.
+func decodeStrict(configFile []byte, v interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(configFile))
+	decoder.KnownFields(true)
+	err := decoder.Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		for i, problem := range typeErr.Errors {
+			typeErr.Errors[i] = unknownKeyPattern.ReplaceAllString(problem, `unknown key "$1"`)
+		}
+	}
+	return err
+}
+
+// Return the key nodes of the top-level mapping of a document.
+func topLevelKeys(document *yaml.Node) []*yaml.Node {
+	node := document
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var keys []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i])
+	}
+	return keys
+}
+
+// configPathEnv names the configuration file when --file is not given.
+const configPathEnv = "GOMTP_CONFIG"
+
+// Return the configuration file to use: the --file path, then GOMTP_CONFIG,
+// then the first existing file of the search path.
+func findConfig(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if path := os.Getenv(configPathEnv); path != "" {
+		return path, nil
+	}
+	candidates := configSearchPath()
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); !errors.Is(err, fs.ErrNotExist) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no configuration file found in %s; create one with 'gomtp template' or pass --file", strings.Join(candidates, ", "))
+}
+
+// Return the files searched for a configuration, in order.
+func configSearchPath() []string {
+	candidates := []string{"gomtp.yaml"}
+	home, homeErr := os.UserHomeDir()
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" && homeErr == nil {
+		configHome = filepath.Join(home, ".config")
+	}
+	if configHome != "" {
+		candidates = append(candidates, filepath.Join(configHome, "gomtp", "config.yaml"))
+	}
+	if homeErr == nil {
+		candidates = append(candidates, filepath.Join(home, ".gomtp.yaml"))
+	}
+	return candidates
+}
+
+func addFileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&gomtpYamlPath, "file", "f", "", "Configuration file path. Defaults to $GOMTP_CONFIG, ./gomtp.yaml, $XDG_CONFIG_HOME/gomtp/config.yaml or ~/.gomtp.yaml.")
+}
+
+// defaultProfile is used when --profile is not given and the file has several profiles.
+const defaultProfile = "default"
+
+// Find and read the YAML configuration file, then apply the GOMTP_* environment variables.
+// Without a file the environment and the --host flag can provide the configuration.
+func loadConfig(path, profile string) (gomtpConfig, error) {
+	config := newConfig()
+	path, err := findConfig(path)
+	if err != nil {
+		if profile != "" {
+			return config, err
+		}
+		applied, envErr := applyEnv(&config.EmailConfig)
+		if envErr != nil || len(applied) > 0 || host != "" {
+			return config, envErr
+		}
+		return config, err
+	}
+	config, err = readConfig(path, profile)
+	if err != nil {
+		return config, err
+	}
+	_, err = applyEnv(&config.EmailConfig)
+	return config, err
+}
+
+// Read the YAML configuration file. A file with profiles uses the named profile;
+// a flat file is the configuration itself.
+func readConfig(path, profile string) (gomtpConfig, error) {
+	config := newConfig()
+	configFile, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	file, hasProfiles, err := parseProfiles(path, configFile)
+	if err != nil {
+		return config, err
+	}
+	if !hasProfiles {
+		if profile != "" {
+			return config, fmt.Errorf("%s has no profiles; remove --profile or add a profiles section", path)
+		}
+		if err := decodeStrict(configFile, &config); err != nil {
+			return config, fmt.Errorf("%s: %w", path, err)
+		}
+		return config, nil
+	}
+
+	name, err := file.selectProfile(path, profile)
+	if err != nil {
+		return config, err
+	}
+	return file.config(name)
+}
+
+// Read the YAML configuration file into an email config.
+func loadEmailConfig(path, profile string) (smtpclient.EmailConfig, error) {
+	config, err := loadConfig(path, profile)
+	return config.EmailConfig, err
+}
+
+// Parse the profiles and defaults of a configuration file. hasProfiles is false for a flat file.
+func parseProfiles(path string, configFile []byte) (file profilesFile, hasProfiles bool, err error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(configFile, &document); err != nil {
+		return file, false, fmt.Errorf("%s: %w", path, err)
+	}
+	keys := topLevelKeys(&document)
+	for _, key := range keys {
+		if key.Value == "profiles" {
+			hasProfiles = true
+		}
+	}
+	if !hasProfiles {
+		return file, false, nil
+	}
+	for _, key := range keys {
+		if key.Value != "profiles" && key.Value != "defaults" {
+			return file, true, fmt.Errorf("%s: line %d: move %q under defaults or a profile; a file with profiles only has defaults and profiles at the top level", path, key.Line, key.Value)
+		}
+	}
+	if err := decodeStrict(configFile, &profilesConfig{}); err != nil {
+		return file, true, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(configFile, &file); err != nil {
+		return file, true, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(file.Profiles) == 0 {
+		return file, true, fmt.Errorf("%s: profiles is empty", path)
+	}
+	return file, true, nil
+}
+
+// Return the profile to use: the requested one, else "default", else the only profile.
+func (file profilesFile) selectProfile(path, profile string) (string, error) {
+	if profile != "" {
+		if _, ok := file.Profiles[profile]; !ok {
+			return "", fmt.Errorf("profile %q not found in %s; available profiles: %s", profile, path, strings.Join(file.names(), ", "))
+		}
+		return profile, nil
+	}
+	if _, ok := file.Profiles[defaultProfile]; ok {
+		return defaultProfile, nil
+	}
+	if len(file.Profiles) == 1 {
+		return file.names()[0], nil
+	}
+	return "", fmt.Errorf("%s has several profiles; select one with --profile: %s", path, strings.Join(file.names(), ", "))
+}
+
+// Build the configuration of a profile by applying it on top of the defaults.
+func (file profilesFile) config(name string) (gomtpConfig, error) {
+	config := newConfig()
+	for _, section := range file.sections(name) {
+		if err := section.Decode(&config); err != nil {
+			return config, fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+	return config, nil
+}
+
+// Return the defaults and the profile, skipping the ones the file does not have.
+func (file profilesFile) sections(name string) []*yaml.Node {
+	var sections []*yaml.Node
+	profile := file.Profiles[name]
+	for _, section := range []*yaml.Node{&file.Defaults, &profile} {
+		if section.Kind != 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// Return the profile names in order.
+func (file profilesFile) names() []string {
+	names := make([]string, 0, len(file.Profiles))
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gomtp/smtpclient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const profilesYaml = "../tests/gomtpYamls/successConfigurationWithProfiles.yaml"
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gomtp.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadConfigFlat(t *testing.T) {
+	config, err := loadConfig("../tests/gomtpYamls/successConfiguration.yaml", "")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", config.Host)
+	assert.Equal(t, smtpclient.AddressList{"to@example.com"}, config.To)
+
+	_, err = loadConfig("../tests/gomtpYamls/successConfiguration.yaml", "relay-eu")
+	assert.EqualError(t, err, "../tests/gomtpYamls/successConfiguration.yaml has no profiles; remove --profile or add a profiles section")
+}
+
+func TestLoadConfigProfiles(t *testing.T) {
+	config, err := loadConfig(profilesYaml, "")
+	require.NoError(t, err)
+	assert.Equal(t, smtpclient.AddressList{"default-profile@example.com"}, config.To, "the default profile is used without --profile")
+	assert.Equal(t, "from@example.com", config.From)
+	assert.Equal(t, 1025, config.Port)
+
+	config, err = loadConfig(profilesYaml, "relay-eu")
+	require.NoError(t, err)
+	assert.Equal(t, "Relay EU <relay-eu@example.com>", config.From, "the profile overrides the defaults")
+	assert.Equal(t, "Relay EU test", config.Subject)
+	assert.Equal(t, "127.0.0.1", config.Host, "the defaults fill in what the profile does not set")
+	assert.Equal(t, map[string]string{"X-Relay": "default", "X-Relay-Region": "eu"}, config.Headers, "maps are merged")
+
+	_, err = loadConfig(profilesYaml, "relay-us")
+	assert.EqualError(t, err, `profile "relay-us" not found in `+profilesYaml+"; available profiles: default, relay-eu, unreachable")
+}
+
+func TestLoadConfigProfileSelection(t *testing.T) {
+	path := writeConfig(t, "profiles:\n  only:\n    host: 'smtp.example.com'\n")
+	config, err := loadConfig(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "smtp.example.com", config.Host, "a single profile is used without --profile")
+
+	path = writeConfig(t, "profiles:\n  a:\n    port: 25\n  b:\n    port: 587\n")
+	_, err = loadConfig(path, "")
+	assert.EqualError(t, err, path+" has several profiles; select one with --profile: a, b")
+	config, err = loadConfig(path, "b")
+	require.NoError(t, err)
+	assert.Equal(t, 587, config.Port)
+}
+
+func TestLoadConfigProfilesErrors(t *testing.T) {
+	_, err := loadConfig("../tests/gomtpYamls/invalidProfilesWithTopLevelKeys.yaml", "")
+	assert.EqualError(t, err, `../tests/gomtpYamls/invalidProfilesWithTopLevelKeys.yaml: line 1: move "host" under defaults or a profile; a file with profiles only has defaults and profiles at the top level`)
+
+	path := writeConfig(t, "profiles: {}\n")
+	_, err = loadConfig(path, "")
+	assert.EqualError(t, err, path+": profiles is empty")
+
+	path = writeConfig(t, "profiles:\n  default:\n    port: 'not a number'\n")
+	_, err = loadConfig(path, "")
+	assert.EqualError(t, err, path+": yaml: unmarshal errors:\n  line 3: cannot unmarshal !!str `not a n...` into int")
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	path := "../tests/gomtpYamls/invalidConfigurationWithProblems.yaml"
+	_, err := loadConfig(path, "")
+	assert.EqualError(t, err, path+": yaml: unmarshal errors:\n  line 10: unknown key \"verifyCertifcate\"")
+
+	_, err = loadConfig("../tests/gomtpYamls/invalidProfiles.yaml", "relay-eu")
+	assert.ErrorContains(t, err, `line 10: unknown key "prot"`, "every profile is checked, not only the selected one")
+}
+
+func TestLoadConfigVerifyCertificateDefault(t *testing.T) {
+	config, err := loadConfig("../tests/gomtpYamls/successConfiguration.yaml", "")
+	require.NoError(t, err)
+	assert.True(t, config.VerifiesCertificate(), "an omitted verifyCertificate verifies")
+
+	config, err = loadConfig(writeConfig(t, "verifyCertificate: false\n"), "")
+	require.NoError(t, err)
+	assert.False(t, config.VerifiesCertificate())
+
+	config, err = loadConfig(writeConfig(t, "defaults:\n  verifyCertificate: false\nprofiles:\n  a: {}\n  default:\n    verifyCertificate: true\n"), "a")
+	require.NoError(t, err)
+	assert.False(t, config.VerifiesCertificate(), "the defaults section applies")
+
+	isolateConfigSearch(t)
+	t.Setenv("GOMTP_HOST", "smtp.example.com")
+	config, err = loadConfig("", "")
+	require.NoError(t, err)
+	assert.True(t, config.VerifiesCertificate(), "the default applies without a file")
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const configPathUsageMessage = `Print the configuration file gomtp uses. Without --file it is the first of:
+  $GOMTP_CONFIG
+  ./gomtp.yaml
+  $XDG_CONFIG_HOME/gomtp/config.yaml (~/.config/gomtp/config.yaml when XDG_CONFIG_HOME is not set)
+  ~/.gomtp.yaml
+`
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration file.",
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the configuration file in use.",
+	Long:  configPathUsageMessage,
+	Args:  cobra.NoArgs,
+	RunE:  configPathRun,
+}
+
+const configValidateUsageMessage = `Check the configuration file for unknown keys, wrong types and settings that would fail
+a send, without connecting. Every problem is reported with its line. A file with profiles
+is checked profile by profile, and GOMTP_* environment variables are applied as for a send.
+
+Example commands:
+  gomtp config validate # Check the configuration file found by 'gomtp config path'.
+  gomtp config validate -f custom.yaml # Check custom.yaml.
+`
+
+var configValidateCmd = &cobra.Command{
+	Use:          "validate",
+	Short:        "Check the configuration file without connecting.",
+	Long:         configValidateUsageMessage,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         configValidateRun,
+}
+
+func configPathRun(cmd *cobra.Command, args []string) error {
+	path, err := findConfig(gomtpYamlPath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), path)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configValidateCmd)
+	addFileFlag(configPathCmd)
+	addFileFlag(configValidateCmd)
+}
+
+func configValidateRun(cmd *cobra.Command, args []string) error {
+	path, err := findConfig(gomtpYamlPath)
+	if err != nil {
+		return err
+	}
+	problems, err := checkConfig(path)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(cmd.OutOrStdout(), problem)
+	}
+	switch len(problems) {
+	case 0:
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", path)
+		return nil
+	case 1:
+		return fmt.Errorf("%s has 1 problem", path)
+	default:
+		return fmt.Errorf("%s has %d problems", path, len(problems))
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run the test from an empty directory with an empty home and no GOMTP_CONFIG.
+func isolateConfigSearch(t *testing.T) (dir, home string) {
+	t.Helper()
+	dir, home = t.TempDir(), t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(configPathEnv, "")
+	return dir, home
+}
+
+func touch(t *testing.T, path string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, os.WriteFile(path, []byte("host: '127.0.0.1'\n"), 0600))
+}
+
+func TestFindConfig(t *testing.T) {
+	_, home := isolateConfigSearch(t)
+
+	_, err := findConfig("")
+	assert.EqualError(t, err, "no configuration file found in gomtp.yaml, "+
+		filepath.Join(home, ".config/gomtp/config.yaml")+", "+filepath.Join(home, ".gomtp.yaml")+
+		"; create one with 'gomtp template' or pass --file")
+
+	touch(t, filepath.Join(home, ".gomtp.yaml"))
+	path, err := findConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".gomtp.yaml"), path)
+
+	touch(t, filepath.Join(home, ".config/gomtp/config.yaml"))
+	path, err = findConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".config/gomtp/config.yaml"), path)
+
+	xdg := filepath.Join(home, "xdg")
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	touch(t, filepath.Join(xdg, "gomtp/config.yaml"))
+	path, err = findConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(xdg, "gomtp/config.yaml"), path)
+
+	touch(t, "gomtp.yaml")
+	path, err = findConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "gomtp.yaml", path)
+
+	t.Setenv(configPathEnv, "/etc/gomtp/relays.yaml")
+	path, err = findConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/gomtp/relays.yaml", path, "GOMTP_CONFIG wins over the search path")
+
+	path, err = findConfig("custom.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "custom.yaml", path, "--file wins over GOMTP_CONFIG")
+}
+
+func TestConfigPathCommand(t *testing.T) {
+	defer resetFlags()
+	_, home := isolateConfigSearch(t)
+	touch(t, filepath.Join(home, ".gomtp.yaml"))
+
+	command := rootCmd
+	command.SetArgs([]string{"config", "path"})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(home, ".gomtp.yaml")+"\n", b.String())
+}
+
+func TestConfigPathCommandNotFound(t *testing.T) {
+	defer resetFlags()
+	isolateConfigSearch(t)
+	t.Setenv(configPathEnv, "missing.yaml")
+
+	command := rootCmd
+	command.SetArgs([]string{"config", "path"})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.EqualError(t, err, "stat missing.yaml: no such file or directory")
+}
+
+func runConfigValidate(t *testing.T, path string) (string, error) {
+	t.Helper()
+	resetFlags()
+	defer resetFlags()
+	command := rootCmd
+	command.SetArgs([]string{"config", "validate", "--file", path})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(bytes.NewBufferString(""))
+	err := command.Execute()
+	return b.String(), err
+}
+
+func TestConfigValidateCommand(t *testing.T) {
+	output, err := runConfigValidate(t, "../tests/gomtpYamls/successConfiguration.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, "../tests/gomtpYamls/successConfiguration.yaml is valid\n", output)
+}
+
+func TestConfigValidateCommandProblems(t *testing.T) {
+	path := "../tests/gomtpYamls/invalidConfigurationWithProblems.yaml"
+	output, err := runConfigValidate(t, path)
+	assert.EqualError(t, err, path+" has 5 problems")
+	assert.Equal(t, path+`:4: invalid to address "bad@": mail: missing '@' or angle-addr
+`+path+`:6: port 70000 is out of range 1-65535
+`+path+`:8: ssl and tls are both enabled; use ssl for implicit TLS or tls for STARTTLS
+`+path+`:9: auth PLAIN needs a username
+`+path+`:10: unknown key "verifyCertifcate"
+`, output)
+}
+
+func TestConfigValidateCommandProfiles(t *testing.T) {
+	path := "../tests/gomtpYamls/invalidProfiles.yaml"
+	output, err := runConfigValidate(t, path)
+	assert.EqualError(t, err, path+" has 2 problems")
+	assert.Equal(t, path+`:5: profile relay-us: auth PLAIN needs a username
+`+path+`:10: unknown key "prot"
+`, output)
+
+	output, err = runConfigValidate(t, profilesYaml)
+	assert.Nil(t, err)
+	assert.Equal(t, profilesYaml+" is valid\n", output)
+}
+
+func TestConfigValidateCommandSyntaxError(t *testing.T) {
+	path := "../tests/gomtpYamls/invalid.yaml"
+	output, err := runConfigValidate(t, path)
+	assert.EqualError(t, err, path+" has 1 problem")
+	assert.Equal(t, path+":9: mapping values are not allowed in this context\n", output)
+}
+
+func TestConfigValidateCommandEnv(t *testing.T) {
+	t.Setenv("GOMTP_PORT", "0")
+	path := "../tests/gomtpYamls/successConfiguration.yaml"
+	output, err := runConfigValidate(t, path)
+	assert.EqualError(t, err, path+" has 1 problem")
+	assert.Equal(t, "GOMTP_PORT: port is not set\n", output)
+}
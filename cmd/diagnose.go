@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gomtp/smtpclient"
+)
+
+// Print the diagnosis of a certificate verification failure. Other errors print nothing.
+func printCertificateDiagnosis(w io.Writer, err error) {
+	var certErr *smtpclient.CertificateError
+	if !errors.As(err, &certErr) {
+		return
+	}
+	diagnosis := certErr.Diagnosis
+	fmt.Fprintf(w, "Certificate verification failed for %s\n", diagnosis.Host)
+	fmt.Fprintln(w, "Presented chain:")
+	printChain(w, diagnosis.Chain)
+	fmt.Fprintf(w, "OCSP staple: %s\n", diagnosis.OCSP)
+	fmt.Fprintln(w, "Problems:")
+	for _, problem := range diagnosis.Problems {
+		fmt.Fprintf(w, "  - %s\n", problem.Problem)
+		if problem.Suggestion != "" {
+			fmt.Fprintf(w, "    Fix: %s\n", problem.Suggestion)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gomtp/smtpclient"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintCertificateDiagnosis(t *testing.T) {
+	notBefore := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	certErr := &smtpclient.CertificateError{
+		Err: errors.New("tls: failed to verify certificate: x509: certificate is valid for mail.example.com, not smtp.example.com"),
+		Diagnosis: &smtpclient.ChainDiagnosis{
+			Host: "smtp.example.com",
+			Chain: []smtpclient.CertificateInfo{
+				{Subject: "CN=mail.example.com", Issuer: "CN=Example CA", NotBefore: notBefore, NotAfter: notAfter, DNSNames: []string{"mail.example.com"}, PinSHA256: "bGVhZg=="},
+				{Subject: "CN=Example CA", Issuer: "CN=Example Root", NotBefore: notBefore, NotAfter: notAfter, PinSHA256: "Y2E="},
+			},
+			OCSP: "not stapled",
+			Problems: []smtpclient.ChainProblem{
+				{Problem: "the server certificate is valid for mail.example.com, not smtp.example.com", Suggestion: "set host to a name the certificate is valid for"},
+			},
+		},
+	}
+
+	var b bytes.Buffer
+	printCertificateDiagnosis(&b, fmt.Errorf("starttls: %w", certErr))
+	assert.Equal(t, `Certificate verification failed for smtp.example.com
+Presented chain:
+  [0] Subject: CN=mail.example.com
+      Issuer: CN=Example CA
+      Valid: 2026-01-01T00:00:00Z to 2026-04-01T00:00:00Z
+      DNS names: mail.example.com
+      Pin SHA-256: bGVhZg==
+  [1] Subject: CN=Example CA
+      Issuer: CN=Example Root
+      Valid: 2026-01-01T00:00:00Z to 2026-04-01T00:00:00Z
+      Pin SHA-256: Y2E=
+OCSP staple: not stapled
+Problems:
+  - the server certificate is valid for mail.example.com, not smtp.example.com
+    Fix: set host to a name the certificate is valid for
+`, b.String())
+
+	b.Reset()
+	printCertificateDiagnosis(&b, errors.New("connect: connection refused"))
+	assert.Empty(t, b.String())
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gomtp/smtpclient"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix starts the name of every environment variable that overrides the configuration.
+const envPrefix = "GOMTP_"
+
+// envAddressKeys are the lists of addresses, whose display names may contain commas.
+var envAddressKeys = map[string]bool{"to": true, "cc": true, "bcc": true, "replyTo": true}
+
+// Override the config with GOMTP_* environment variables named after the YAML keys,
+// e.g. GOMTP_HOST for host and GOMTP_OAUTH2_CLIENT_ID for oauth2.clientId.
+// It returns the keys that were set, e.g. "host" and "oauth2.clientId".
+func applyEnv(emailConfig *smtpclient.EmailConfig) ([]string, error) {
+	return applyEnvFields(reflect.ValueOf(emailConfig).Elem(), "")
+}
+
+func applyEnvFields(v reflect.Value, prefix string) ([]string, error) {
+	var applied []string
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		key = prefix + key
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			keys, err := applyEnvFields(field, key+".")
+			applied = append(applied, keys...)
+			if err != nil {
+				return applied, err
+			}
+			continue
+		}
+
+		name := envVariable(key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		setValue := setEnvValue
+		if envAddressKeys[key] {
+			setValue = setEnvAddresses
+		}
+		if err := setValue(field, value); err != nil {
+			return applied, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		applied = append(applied, key)
+	}
+	return applied, nil
+}
+
+// Return the environment variable of a key, e.g. GOMTP_OAUTH2_CLIENT_ID for oauth2.clientId.
+func envVariable(key string) string {
+	var parts []string
+	for _, part := range strings.Split(key, ".") {
+		parts = append(parts, envName(part))
+	}
+	return envPrefix + strings.Join(parts, "_")
+}
+
+// Set a field from an environment variable. Lists of strings are comma separated;
+// other lists and maps are written in YAML flow style, e.g. {X-Priority: '1'}.
+func setEnvValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		field.SetBool(b)
+	case reflect.Pointer:
+		// Optional settings such as verifyCertificate, where nil keeps the default
+		field.Set(reflect.New(field.Type().Elem()))
+		return setEnvValue(field.Elem(), value)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String {
+			var items []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			field.Set(reflect.ValueOf(items).Convert(field.Type()))
+			return nil
+		}
+		fallthrough
+	default:
+		field.Set(reflect.Zero(field.Type()))
+		return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+	return nil
+}
+
+// Set a list of addresses, parsed as RFC 5322 so that "Doe, John" <j@example.com> stays one address.
+func setEnvAddresses(field reflect.Value, value string) error {
+	var items []string
+	if strings.TrimSpace(value) != "" {
+		addresses, err := mail.ParseAddressList(value)
+		if err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			if address.Name == "" {
+				items = append(items, address.Address)
+			} else {
+				items = append(items, address.String())
+			}
+		}
+	}
+	field.Set(reflect.ValueOf(items).Convert(field.Type()))
+	return nil
+}
+
+// Convert a camelCase YAML key to UPPER_SNAKE_CASE, e.g. verifyCertificate to VERIFY_CERTIFICATE.
+func envName(key string) string {
+	var name strings.Builder
+	for i, r := range key {
+		if i > 0 && unicode.IsUpper(r) && !unicode.IsUpper(rune(key[i-1])) {
+			name.WriteByte('_')
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"testing"
+
+	"gomtp/smtpclient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "HOST", envName("host"))
+	assert.Equal(t, "VERIFY_CERTIFICATE", envName("verifyCertificate"))
+	assert.Equal(t, "BODY_HTML", envName("bodyHtml"))
+	assert.Equal(t, "OAUTH2", envName("oauth2"))
+	assert.Equal(t, "TOKEN_URL", envName("tokenUrl"))
+	assert.Equal(t, "GOMTP_OAUTH2_CLIENT_ID", envVariable("oauth2.clientId"))
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("GOMTP_HOST", "smtp.example.com")
+	t.Setenv("GOMTP_PORT", "587")
+	t.Setenv("GOMTP_TLS", "true")
+	t.Setenv("GOMTP_VERIFY_CERTIFICATE", "false")
+	t.Setenv("GOMTP_PASSWORD", "secret")
+	t.Setenv("GOMTP_TO", `"Doe, John" <j@example.com>, dev@example.com`)
+	t.Setenv("GOMTP_CC", "")
+	t.Setenv("GOMTP_OAUTH2_CLIENT_ID", "client")
+	t.Setenv("GOMTP_OAUTH2_SCOPES", "a,b")
+	t.Setenv("GOMTP_HEADERS", "{X-Priority: '1'}")
+	t.Setenv("GOMTP_ATTACHMENTS", "[report.pdf, {path: logo.png, inline: true}]")
+
+	emailConfig := smtpclient.EmailConfig{
+		Host:     "127.0.0.1",
+		Username: "user",
+		CcList:   []string{"cc@example.com"},
+		Headers:  map[string]string{"X-Campaign": "spring"},
+	}
+	applied, err := applyEnv(&emailConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"password", "to", "host", "port", "tls", "verifyCertificate", "cc", "oauth2.clientId", "oauth2.scopes", "attachments", "headers"}, applied)
+	assert.Equal(t, smtpclient.EmailConfig{
+		Host:              "smtp.example.com",
+		Port:              587,
+		TLS:               true,
+		Username:          "user",
+		Password:          "secret",
+		To:                smtpclient.AddressList{`"Doe, John" <j@example.com>`, "dev@example.com"},
+		VerifyCertificate: new(bool),
+		OAuth2:            smtpclient.OAuth2Config{ClientID: "client", Scopes: []string{"a", "b"}},
+		Headers:           map[string]string{"X-Priority": "1"},
+		Attachments: []smtpclient.Attachment{
+			{Path: "report.pdf"},
+			{Path: "logo.png", Inline: true},
+		},
+	}, emailConfig)
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{"GOMTP_PORT", "smtp", `invalid GOMTP_PORT: "smtp" is not a number`},
+		{"GOMTP_SSL", "maybe", `invalid GOMTP_SSL: "maybe" is not true or false`},
+		{"GOMTP_VERIFY_CERTIFICATE", "maybe", `invalid GOMTP_VERIFY_CERTIFICATE: "maybe" is not true or false`},
+		{"GOMTP_HEADERS", "[a", "invalid GOMTP_HEADERS: yaml: "},
+		{"GOMTP_CC", "cc@", "invalid GOMTP_CC: mail: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+			_, err := applyEnv(&smtpclient.EmailConfig{})
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadConfigEnvOverridesYaml(t *testing.T) {
+	t.Setenv("GOMTP_SUBJECT", "Subject from env")
+	config, err := loadConfig("../tests/gomtpYamls/successConfiguration.yaml", "")
+	require.NoError(t, err)
+	assert.Equal(t, "Subject from env", config.Subject)
+	assert.Equal(t, "127.0.0.1", config.Host)
+
+	config, err = loadConfig(profilesYaml, "relay-eu")
+	require.NoError(t, err)
+	assert.Equal(t, "Subject from env", config.Subject, "env overrides the profile too")
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	isolateConfigSearch(t)
+	_, err := loadConfig("", "")
+	assert.ErrorContains(t, err, "no configuration file found")
+
+	t.Setenv("GOMTP_HOST", "smtp.example.com")
+	config, err := loadConfig("", "")
+	require.NoError(t, err)
+	assert.Equal(t, "smtp.example.com", config.Host)
+
+	_, err = loadConfig("", "relay-eu")
+	assert.ErrorContains(t, err, "no configuration file found", "a profile needs a file")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gomtp/smtpclient"
+
+	"github.com/spf13/cobra"
+)
+
+func addWarnExpiryFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&warnExpiry, "warn-expiry", "", fmt.Sprintf("Exit with code %d when a certificate of the server chain expires within this window, e.g. 14d or 36h.", exitCertificateExpiry))
+}
+
+// Parse a --warn-expiry value: a number of days such as 14d, or a Go duration such as 36h.
+// An empty value disables the check.
+func parseExpiryWindow(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		window = time.Duration(n) * 24 * time.Hour
+	}
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("invalid --warn-expiry %q: use a duration such as 14d or 36h", value)
+	}
+	return window, nil
+}
+
+// Return an exitError listing the certificates that expire within the window, if any.
+func checkExpiry(result *smtpclient.Result, window time.Duration) error {
+	if window == 0 {
+		return nil
+	}
+	if result == nil || result.TLS == nil {
+		return errors.New("--warn-expiry needs ssl or tls to check the server certificate")
+	}
+	var expiring []string
+	for _, cert := range result.TLS.ExpiringCertificates(window) {
+		state := "expires"
+		if cert.NotAfter.Before(time.Now()) {
+			state = "expired"
+		}
+		expiring = append(expiring, fmt.Sprintf("%s %s %s", cert.Subject, state, cert.NotAfter.Format(time.RFC3339)))
+	}
+	if len(expiring) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("certificate expires within %s: %s", warnExpiry, strings.Join(expiring, "; "))
+	return &exitError{code: exitCertificateExpiry, err: err}
+}
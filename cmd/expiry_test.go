@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"gomtp/smtpclient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpiryWindow(t *testing.T) {
+	tests := []struct {
+		value  string
+		window time.Duration
+		err    bool
+	}{
+		{"", 0, false},
+		{"14d", 14 * 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"1.5d", 0, true},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		window, err := parseExpiryWindow(tt.value)
+		if tt.err {
+			assert.EqualError(t, err, `invalid --warn-expiry "`+tt.value+`": use a duration such as 14d or 36h`)
+			continue
+		}
+		assert.NoError(t, err, tt.value)
+		assert.Equal(t, tt.window, window, tt.value)
+	}
+}
+
+func TestCheckExpiry(t *testing.T) {
+	resetFlags()
+	defer resetFlags()
+	warnExpiry = "14d"
+	notAfter := time.Now().Add(3 * 24 * time.Hour)
+	result := &smtpclient.Result{TLS: &smtpclient.TLSInfo{Certificates: []smtpclient.CertificateInfo{
+		{Subject: "CN=mail.example.com", NotAfter: notAfter},
+		{Subject: "CN=Example CA", NotAfter: time.Now().Add(365 * 24 * time.Hour)},
+	}}}
+
+	assert.NoError(t, checkExpiry(result, 0), "the check is off by default")
+	assert.NoError(t, checkExpiry(result, 24*time.Hour))
+
+	err := checkExpiry(result, 14*24*time.Hour)
+	assert.EqualError(t, err, "certificate expires within 14d: CN=mail.example.com expires "+notAfter.Format(time.RFC3339))
+	var exitErr *exitError
+	require.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, exitCertificateExpiry, exitErr.code)
+
+	result.TLS.Certificates[1].NotAfter = time.Now().Add(-time.Hour)
+	assert.ErrorContains(t, checkExpiry(result, 14*24*time.Hour), "; CN=Example CA expired ")
+
+	err = checkExpiry(&smtpclient.Result{}, 14*24*time.Hour)
+	assert.EqualError(t, err, "--warn-expiry needs ssl or tls to check the server certificate")
+	assert.False(t, errors.As(err, &exitErr), "a missing TLS connection is an ordinary failure")
+}
+
+func TestProbeCommandWarnExpiryWithoutTLS(t *testing.T) {
+	resetFlags()
+	defer resetFlags()
+	command := rootCmd
+	command.SetArgs([]string{
+		"probe",
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--warn-expiry", "14d",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.EqualError(t, err, "--warn-expiry needs ssl or tls to check the server certificate")
+	assert.Contains(t, b.String(), "TLS: not in use", "the report is printed before the check")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"gomtp/smtpclient"
+)
+
+// Add the --header flags to the headers of the config, replacing headers with the same name.
+func setHeaderFlags(emailConfig *smtpclient.EmailConfig) error {
+	for _, header := range headerList {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return fmt.Errorf("invalid --header %q: use 'Name: value'", header)
+		}
+		if emailConfig.Headers == nil {
+			emailConfig.Headers = map[string]string{}
+		}
+		for existing := range emailConfig.Headers {
+			if strings.EqualFold(existing, name) {
+				delete(emailConfig.Headers, existing)
+			}
+		}
+		emailConfig.Headers[name] = strings.TrimSpace(value)
+	}
+	if overrideHeaders {
+		emailConfig.OverrideHeaders = true
+	}
+	return nil
+}
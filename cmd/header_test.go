@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"testing"
+
+	"gomtp/smtpclient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetHeaderFlags(t *testing.T) {
+	defer resetFlags()
+	headerList = []string{"X-Priority: 1", "x-mailer:gomtp-test", "X-Empty:"}
+	emailConfig := smtpclient.EmailConfig{Headers: map[string]string{"X-Mailer": "configured", "X-Campaign": "spring"}}
+
+	require.NoError(t, setHeaderFlags(&emailConfig))
+	assert.Equal(t, map[string]string{
+		"X-Priority": "1",
+		"x-mailer":   "gomtp-test",
+		"X-Campaign": "spring",
+		"X-Empty":    "",
+	}, emailConfig.Headers)
+	assert.False(t, emailConfig.OverrideHeaders)
+}
+
+func TestSetHeaderFlagsInvalid(t *testing.T) {
+	defer resetFlags()
+	for _, header := range []string{"X-Priority 1", ": value"} {
+		headerList = []string{header}
+		err := setHeaderFlags(&smtpclient.EmailConfig{})
+		assert.Error(t, err, header)
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gomtp/smtpclient"
+)
+
+// Write the send result as indented JSON. Errors raised before connecting have no result yet.
+func printJSONResult(w io.Writer, result *smtpclient.Result, err error) error {
+	if result == nil {
+		result = &smtpclient.Result{Phases: []smtpclient.PhaseResult{}}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// Write the latency of each step as a table. Errors raised before connecting have no result yet.
+func printTimings(w io.Writer, result *smtpclient.Result) {
+	if result == nil {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tTARGET\tDURATION")
+	for _, t := range result.Timings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Step, t.Target, t.Duration.Round(time.Microsecond))
+	}
+	fmt.Fprintf(tw, "total\t\t%s\n", result.Duration.Round(time.Microsecond))
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+// Write the certificate chain presented by the server, leaf first.
+func printChain(w io.Writer, certs []smtpclient.CertificateInfo) {
+	for i, cert := range certs {
+		fmt.Fprintf(w, "  [%d] Subject: %s\n", i, cert.Subject)
+		fmt.Fprintf(w, "      Issuer: %s\n", cert.Issuer)
+		fmt.Fprintf(w, "      Valid: %s to %s\n", cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+		if len(cert.DNSNames) > 0 {
+			fmt.Fprintf(w, "      DNS names: %s\n", strings.Join(cert.DNSNames, ", "))
+		}
+		fmt.Fprintf(w, "      Pin SHA-256: %s\n", cert.PinSHA256)
+	}
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"strings"
+
+	"gomtp/smtpclient"
+
+	"github.com/spf13/cobra"
+)
+
+var probeLogin bool
+
+// Extensions reported as supported or not, whether or not the server advertises them.
+var probeExtensions = []string{"SIZE", "PIPELINING", "8BITMIME", "SMTPUTF8", "DSN", "CHUNKING", "ENHANCEDSTATUSCODES", "AUTH"}
+
+const probeUsageMessage = `Connect to the configured SMTP server, report what it supports and disconnect before MAIL FROM.
+
+Example commands:
+  gomtp probe # Probe the server configured in gomtp.yaml.
+  gomtp probe -f custom.yaml # Probe the server configured in custom.yaml.
+  gomtp probe -P relay-eu # Probe the server of the relay-eu profile.
+  gomtp probe --host smtp.example.com --port 587 --starttls # Probe a server without a configuration file.
+  gomtp probe --login # Also verify the configured credentials.
+`
+
+var probeCmd = &cobra.Command{
+	Use:   "probe",
+	Short: "Report SMTP server capabilities without sending an email.",
+	Long:  probeUsageMessage,
+	RunE:  probeRun,
+}
+
+func probeRun(cmd *cobra.Command, args []string) error {
+	window, err := parseExpiryWindow(warnExpiry)
+	if err != nil {
+		return err
+	}
+	emailConfig, err := loadEmailConfig(gomtpYamlPath, profileName)
+	if err != nil {
+		return err
+	}
+	setConnectionFlags(cmd, &emailConfig)
+	if err := validateConfig(&emailConfig, false); err != nil {
+		return err
+	}
+
+	// Inspect the server without credentials first so the report is printed even if AUTH fails
+	probeConfig := emailConfig
+	probeConfig.Auth = smtpclient.AuthNone
+	client, closeTrace, err := newClient(&probeConfig)
+	if err != nil {
+		return err
+	}
+	defer closeTrace()
+	ctx := context.Background()
+	if err := client.Dial(ctx); err != nil {
+		printCertificateDiagnosis(cmd.ErrOrStderr(), err)
+		return err
+	}
+	printProbeReport(cmd.OutOrStdout(), &emailConfig, client)
+	expiryErr := checkExpiry(client.Result(), window)
+	client.Close()
+
+	if err := probeCredentials(cmd.OutOrStdout(), &emailConfig); err != nil {
+		return err
+	}
+	return expiryErr
+}
+
+// Open a second session that authenticates with the configured credentials.
+func probeCredentials(w io.Writer, emailConfig *smtpclient.EmailConfig) error {
+	if !probeLogin {
+		fmt.Fprintln(w, "Authentication: skipped (use --login to verify credentials)")
+		return nil
+	}
+	if emailConfig.Auth == "" || strings.EqualFold(emailConfig.Auth, smtpclient.AuthNone) {
+		fmt.Fprintln(w, "Authentication: not configured (auth is 'NO')")
+		return nil
+	}
+
+	client, closeTrace, err := newClient(emailConfig)
+	if err != nil {
+		return err
+	}
+	defer closeTrace()
+	if err := client.Dial(context.Background()); err != nil {
+		fmt.Fprintln(w, "Authentication: failed")
+		return err
+	}
+	defer client.Close()
+
+	if mechanism := client.AuthMechanism(); mechanism != "" {
+		fmt.Fprintf(w, "Authentication: succeeded (%s)\n", mechanism)
+	} else {
+		fmt.Fprintln(w, "Authentication: skipped (server does not advertise AUTH)")
+	}
+	return nil
+}
+
+func printProbeReport(w io.Writer, emailConfig *smtpclient.EmailConfig, client *smtpclient.Client) {
+	mode := "plain"
+	if emailConfig.SSL {
+		mode = "implicit TLS"
+	} else if emailConfig.TLS {
+		mode = "STARTTLS"
+	}
+	fmt.Fprintf(w, "Server: %s:%d (%s)\n", emailConfig.Host, emailConfig.Port, mode)
+	fmt.Fprintf(w, "Banner: %s\n", strings.ReplaceAll(client.Banner(), "\n", "\n        "))
+
+	fmt.Fprintln(w, "Extensions:")
+	for _, ext := range client.Extensions() {
+		fmt.Fprintf(w, "  %s\n", strings.TrimSpace(ext.Name+" "+ext.Params))
+	}
+
+	fmt.Fprintln(w, "Capabilities:")
+	for _, name := range probeExtensions {
+		value := "no"
+		if ok, params := client.Extension(name); ok {
+			value = "yes"
+			if params != "" {
+				value = params
+			}
+		}
+		fmt.Fprintf(w, "  %-20s %s\n", name, value)
+	}
+
+	state, ok := client.TLSConnectionState()
+	if !ok {
+		fmt.Fprintln(w, "TLS: not in use")
+		return
+	}
+	fmt.Fprintln(w, "TLS:")
+	fmt.Fprintf(w, "  %-20s %s\n", "Version", tls.VersionName(state.Version))
+	fmt.Fprintf(w, "  %-20s %s\n", "Cipher suite", tls.CipherSuiteName(state.CipherSuite))
+	fmt.Fprintf(w, "  %-20s %s\n", "Server name", state.ServerName)
+	fmt.Fprintln(w, "Certificate chain:")
+	printChain(w, client.Result().TLS.Certificates)
+}
+
+func init() {
+	rootCmd.AddCommand(probeCmd)
+	addFileFlag(probeCmd)
+	probeCmd.Flags().StringVarP(&profileName, "profile", "P", "", "Profile of the configuration file to use.")
+	addConnectionFlags(probeCmd)
+	probeCmd.Flags().BoolVar(&probeLogin, "login", false, "Also authenticate with the configured credentials.")
+	probeCmd.Flags().BoolVar(&debug, "debug", false, "Enable verbose SMTP/TLS debugging output.")
+	addTraceFlags(probeCmd)
+	addWarnExpiryFlag(probeCmd)
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeCommand(t *testing.T) {
+	command := rootCmd
+	command.SetArgs([]string{
+		"probe",
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--login=false",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), "Server: 127.0.0.1:1025 (plain)")
+	assert.Contains(t, b.String(), "Banner: ")
+	assert.Contains(t, b.String(), "8BITMIME")
+	assert.Contains(t, b.String(), "TLS: not in use")
+	assert.Contains(t, b.String(), "Authentication: skipped")
+}
+
+func TestProbeCommandWithAuth(t *testing.T) {
+	command := rootCmd
+	command.SetArgs([]string{
+		"probe",
+		"--file", "../tests/gomtpYamls/successConfigurationWithAuth.yaml",
+		"--login",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), "Authentication: succeeded")
+}
+
+func TestProbeCommandAuthFlag(t *testing.T) {
+	resetFlags()
+	defer resetFlags()
+	command := rootCmd
+	command.SetArgs([]string{
+		"probe",
+		"--file", "../tests/gomtpYamls/successConfigurationWithAuth.yaml",
+		"--login",
+		"--auth", "NO",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), "Authentication: not configured")
+}
+
+func TestProbeCommandNotReachable(t *testing.T) {
+	command := rootCmd
+	command.SetArgs([]string{
+		"probe",
+		"--file", "../tests/gomtpYamls/nonSslServerWithSslConfiguration.yaml",
+		"--login=false",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.NotNil(t, err)
+	assert.NotContains(t, b.String(), "Banner: ")
+}
+
+func TestProbeCommandConnectionFlags(t *testing.T) {
+	resetFlags()
+	defer resetFlags()
+	isolateConfigSearch(t)
+	command := rootCmd
+	command.SetArgs([]string{
+		"probe",
+		"--host", "127.0.0.1",
+		"--port", "1025",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.Nil(t, err)
+	assert.Contains(t, b.String(), "Server: 127.0.0.1:1025 (plain)")
+}
+
+func TestProbeCommandStartTLSFlagReplacesSSL(t *testing.T) {
+	resetFlags()
+	defer resetFlags()
+	command := rootCmd
+	command.SetArgs([]string{
+		"probe",
+		"--file", "../tests/gomtpYamls/nonSslServerWithSslConfiguration.yaml",
+		"--starttls",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.EqualError(t, err, "starttls: server does not support STARTTLS", "ssl: true is replaced, so STARTTLS is tried")
+	assert.NotContains(t, b.String(), "both enabled")
+}
+
+func TestProbeCommandFileNotFound(t *testing.T) {
+	command := rootCmd
+	command.SetArgs([]string{
+		"probe",
+		"--file", "../unknown/path.yaml",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.NotNil(t, err)
+	assert.Contains(t, b.String(), "no such file or directory")
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+const profilesListUsageMessage = `List the profiles of the configuration file with their server and sender.
+
+Example commands:
+  gomtp profiles list # List the profiles of the configuration file found by 'gomtp config path'.
+  gomtp profiles list -f relays.yaml # List the profiles of relays.yaml.
+`
+
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "Manage the profiles of the configuration file.",
+}
+
+var profilesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles of the configuration file.",
+	Long:  profilesListUsageMessage,
+	Args:  cobra.NoArgs,
+	RunE:  profilesListRun,
+}
+
+func profilesListRun(cmd *cobra.Command, args []string) error {
+	path, err := findConfig(gomtpYamlPath)
+	if err != nil {
+		return err
+	}
+	configFile, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	file, hasProfiles, err := parseProfiles(path, configFile)
+	if err != nil {
+		return err
+	}
+	if !hasProfiles {
+		return fmt.Errorf("%s has no profiles", path)
+	}
+	selected, _ := file.selectProfile(path, "")
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROFILE\tSERVER\tFROM")
+	for _, name := range file.names() {
+		config, err := file.config(name)
+		if err != nil {
+			return err
+		}
+		if name == selected {
+			name += " (default)"
+		}
+		fmt.Fprintf(tw, "%s\t%s:%d\t%s\n", name, config.Host, config.Port, config.From)
+	}
+	return tw.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(profilesCmd)
+	profilesCmd.AddCommand(profilesListCmd)
+	addFileFlag(profilesListCmd)
+}
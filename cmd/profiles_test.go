@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfilesListCommand(t *testing.T) {
+	command := rootCmd
+	command.SetArgs([]string{
+		"profiles", "list",
+		"--file", "../tests/gomtpYamls/successConfigurationWithProfiles.yaml",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, `PROFILE            SERVER          FROM
+default (default)  127.0.0.1:1025  from@example.com
+relay-eu           127.0.0.1:1025  Relay EU <relay-eu@example.com>
+unreachable        127.0.0.1:1     from@example.com
+`, b.String())
+}
+
+func TestProfilesListCommandFlatFile(t *testing.T) {
+	command := rootCmd
+	command.SetArgs([]string{
+		"profiles", "list",
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.EqualError(t, err, "../tests/gomtpYamls/successConfiguration.yaml has no profiles")
+}
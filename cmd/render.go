@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"gomtp/smtpclient"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Templates are only rendered when variables are given, so a body that happens to
+// contain {{, such as a log file, is sent as is. An empty vars key enables them for .Env.
+func usesTemplates(vars map[string]interface{}) bool {
+	return vars != nil || varsFile != "" || len(templateVars) > 0
+}
+
+// Build the data passed to the templates. The vars file, the vars key and the
+// --var flags are merged in that order, later ones winning. .Env holds the environment.
+func templateData(vars map[string]interface{}) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if varsFile != "" {
+		fileVars, err := readVarsFile(varsFile)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fileVars {
+			data[key] = value
+		}
+	}
+	for key, value := range vars {
+		data[key] = value
+	}
+	for _, variable := range templateVars {
+		key, value, ok := strings.Cut(variable, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --var %q: use key=value", variable)
+		}
+		data[key] = value
+	}
+
+	env := map[string]string{}
+	for _, variable := range os.Environ() {
+		key, value, _ := strings.Cut(variable, "=")
+		env[key] = value
+	}
+	data["Env"] = env
+	return data, nil
+}
+
+// Read template variables from a JSON or YAML file.
+func readVarsFile(path string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]interface{}{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &vars)
+	} else {
+		err = yaml.Unmarshal(content, &vars)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid vars file %s: %w", path, err)
+	}
+	return vars, nil
+}
+
+// Render the subject and bodies as Go templates. A body read from stdin is sent as is.
+func renderTemplates(emailConfig *smtpclient.EmailConfig, data map[string]interface{}, renderBody bool) error {
+	var err error
+	if emailConfig.Subject, err = renderText("subject", emailConfig.Subject, data); err != nil {
+		return err
+	}
+	if renderBody {
+		if emailConfig.Body, err = renderText("body", emailConfig.Body, data); err != nil {
+			return err
+		}
+	}
+	emailConfig.BodyHTML, err = renderHTML("bodyHtml", emailConfig.BodyHTML, data)
+	return err
+}
+
+func renderText(name, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := texttemplate.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var rendered strings.Builder
+	err = tmpl.Execute(&rendered, data)
+	return rendered.String(), err
+}
+
+// The HTML body is rendered with html/template so variables are escaped.
+func renderHTML(name, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := htmltemplate.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var rendered strings.Builder
+	err = tmpl.Execute(&rendered, data)
+	return rendered.String(), err
+}
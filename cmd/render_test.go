@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"testing"
+
+	"gomtp/smtpclient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateDataPrecedence(t *testing.T) {
+	defer resetFlags()
+	t.Setenv("GOMTP_TEST_VAR", "from env")
+	varsFile = "../tests/vars/vars.json"
+	templateVars = []string{"status=down"}
+
+	data, err := templateData(map[string]interface{}{"service": "checkout", "status": "ok"})
+	require.NoError(t, err)
+	assert.Equal(t, "checkout", data["service"], "the vars key overrides the vars file")
+	assert.Equal(t, "down", data["status"], "--var overrides the vars key")
+	assert.Equal(t, map[string]interface{}{"name": "db-1"}, data["host"])
+	assert.Equal(t, "from env", data["Env"].(map[string]string)["GOMTP_TEST_VAR"])
+}
+
+func TestTemplateDataYAMLVarsFile(t *testing.T) {
+	defer resetFlags()
+	varsFile = "../tests/vars/vars.yaml"
+
+	data, err := templateData(nil)
+	require.NoError(t, err)
+	emailConfig := smtpclient.EmailConfig{Subject: "{{.service}} on {{.host.name}}"}
+	require.NoError(t, renderTemplates(&emailConfig, data, true))
+	assert.Equal(t, "billing on db-1", emailConfig.Subject)
+}
+
+func TestTemplateDataInvalidVar(t *testing.T) {
+	defer resetFlags()
+	templateVars = []string{"novalue"}
+
+	_, err := templateData(nil)
+	assert.EqualError(t, err, `invalid --var "novalue": use key=value`)
+}
+
+func TestRenderTemplates(t *testing.T) {
+	data := map[string]interface{}{"name": "<b>Ada</b>", "Env": map[string]string{"USER": "ada"}}
+	emailConfig := smtpclient.EmailConfig{
+		Subject:  "Hello {{.name}}",
+		Body:     "Hi {{.name}} ({{.Env.USER}})",
+		BodyHTML: "<p>Hi {{.name}}</p>",
+	}
+
+	require.NoError(t, renderTemplates(&emailConfig, data, true))
+	assert.Equal(t, "Hello <b>Ada</b>", emailConfig.Subject)
+	assert.Equal(t, "Hi <b>Ada</b> (ada)", emailConfig.Body)
+	assert.Equal(t, "<p>Hi &lt;b&gt;Ada&lt;/b&gt;</p>", emailConfig.BodyHTML, "html bodies escape variables")
+}
+
+func TestRenderTemplatesSkipsStdinBody(t *testing.T) {
+	emailConfig := smtpclient.EmailConfig{Body: "log line with {{ braces }}"}
+
+	require.NoError(t, renderTemplates(&emailConfig, map[string]interface{}{}, false))
+	assert.Equal(t, "log line with {{ braces }}", emailConfig.Body)
+}
+
+func TestRenderTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		emailConfig smtpclient.EmailConfig
+		err         string
+	}{
+		{"missing key", smtpclient.EmailConfig{Subject: "{{.missing}}"}, `map has no entry for key "missing"`},
+		{"syntax error", smtpclient.EmailConfig{Body: "{{.name"}, `template: body:1: unclosed action`},
+		{"missing env", smtpclient.EmailConfig{BodyHTML: "{{.Env.GOMTP_UNSET_VAR}}"}, `map has no entry for key "GOMTP_UNSET_VAR"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := renderTemplates(&tt.emailConfig, map[string]interface{}{"Env": map[string]string{}}, true)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
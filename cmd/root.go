@@ -1,52 +1,71 @@
 package cmd
 
 import (
-	"bytes"
-	"crypto/tls"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net"
-	"net/smtp"
 	"os"
-	"strconv"
-	"time"
+
+	"gomtp/smtpclient"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/gomail.v2"
-	"gopkg.in/yaml.v2"
 )
 
 // CLI flags
 var gomtpYamlPath string
-var emailTo string
+var profileName string
+var host string
+var port int
+var ssl bool
+var startTLS bool
+var authMechanism string
+var username string
+var from string
+var insecure bool
+var caFile string
+var caDir string
+var clientCert string
+var clientKey string
+var tlsMinVersion string
+var tlsMaxVersion string
+var cipherSuites []string
+var curvePreferences []string
+var pinSHA256 []string
+var warnExpiry string
+var emailTo []string
+var envelopeFrom string
+var replyTo []string
+var sender string
 var emailSubject string
 var emailBody string
 var emailBodyFile string
+var emailBodyHTML string
+var emailBodyHTMLFile string
+var textFromHTML bool
 var debug bool
 var ccList []string
+var bccList []string
+var outputFormat string
+var timings bool
+var trace bool
+var traceFile string
+var traceRedactBody bool
+var attachList []string
+var headerList []string
+var overrideHeaders bool
+var templateVars []string
+var varsFile string
 
 var version string
 var commitId string
 
-type EmailConfig struct {
-	Username          string   `yaml:"username"`
-	Password          string   `yaml:"password"`
-	From              string   `yaml:"from"`
-	To                string   `yaml:"to"`
-	Host              string   `yaml:"host"`
-	Port              int      `yaml:"port"`
-	SSL               bool     `yaml:"ssl"`
-	TLS               bool     `yaml:"tls"`
-	Auth              string   `yaml:"auth"`
-	VerifyCertificate bool     `default:"true" yaml:"verifyCertificate"`
-	Subject           string   `yaml:"subject"`
-	Body              string   `yaml:"body"`
-	CcList            []string `yaml:"cc"`
-}
-
 const usageMessage = `Example Commands: 
   gomtp # Read the gomtp.yaml file and send a test email.
-  gomtp -f custom.yaml # Read the custom.yaml file and send a test email.`
+  gomtp -f custom.yaml # Read the custom.yaml file and send a test email.
+  gomtp -P relay-eu # Send a test email with the relay-eu profile of gomtp.yaml.
+  GOMTP_HOST=smtp.example.com GOMTP_PORT=25 gomtp # Override the configuration with GOMTP_* environment variables.`
 
 // RootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -58,60 +77,129 @@ var rootCmd = &cobra.Command{
 }
 
 func rootRun(cmd *cobra.Command, args []string) error {
-	// Read the YAML configuration file
-	configFile, err := os.ReadFile(gomtpYamlPath)
+	if outputFormat != "text" && outputFormat != "json" {
+		return fmt.Errorf("output can be one of these: text | json")
+	}
+
+	// Keep stdout a single JSON document in json mode
+	out := cmd.OutOrStdout()
+	if outputFormat == "json" {
+		out = io.Discard
+	}
+	window, err := parseExpiryWindow(warnExpiry)
 	if err != nil {
 		return err
 	}
-	var emailConfig EmailConfig
-	err = yaml.Unmarshal(configFile, &emailConfig)
+	result, err := runSend(cmd, out)
+	if outputFormat == "json" {
+		if printErr := printJSONResult(cmd.OutOrStdout(), result, err); printErr != nil {
+			return printErr
+		}
+		if err != nil {
+			return err
+		}
+		return checkExpiry(result, window)
+	}
+	if timings {
+		printTimings(cmd.OutOrStdout(), result)
+	}
 	if err != nil {
+		printCertificateDiagnosis(cmd.ErrOrStderr(), err)
+		return err
+	}
+
+	cmd.Printf("Email sent successfully!")
+	if err := checkExpiry(result, window); err != nil {
+		cmd.Println()
 		return err
 	}
+	return nil
+}
+
+// Build the email from the configuration, flags and stdin, then send it.
+// The attachments are reported to out before connecting.
+func runSend(cmd *cobra.Command, out io.Writer) (*smtpclient.Result, error) {
+	// Read the YAML configuration file
+	config, err := loadConfig(gomtpYamlPath, profileName)
+	if err != nil {
+		return nil, err
+	}
+	emailConfig := config.EmailConfig
 
 	// Read the email body from stdin if provided
 	stdioBody, err := readBodyFromStdin()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Set body input
 	err = setBody(&emailConfig, stdioBody)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	err = setBodyHTML(&emailConfig)
+	if err != nil {
+		return nil, err
 	}
 
 	setupDefaultEmailConfig(&emailConfig)
 
-	setFlags(&emailConfig)
+	setFlags(cmd, &emailConfig)
 
-	// Create the email message
-	emailMessage := createEmailMessage(&emailConfig)
+	// Render templates before connecting so template errors never open a session
+	if usesTemplates(config.Vars) {
+		data, err := templateData(config.Vars)
+		if err != nil {
+			return nil, err
+		}
+		if err := renderTemplates(&emailConfig, data, stdioBody == ""); err != nil {
+			return nil, err
+		}
+	}
 
-	err = sendEmail(&emailConfig, emailMessage)
+	if err := setAttachFlags(&emailConfig); err != nil {
+		return nil, err
+	}
+	if err := setHeaderFlags(&emailConfig); err != nil {
+		return nil, err
+	}
+	if err := validateConfig(&emailConfig, true); err != nil {
+		return nil, err
+	}
+	attachments, err := smtpclient.ResolveAttachments(emailConfig.Attachments)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	printAttachments(out, attachments)
 
-	cmd.Printf("Email sent successfully!")
-	return nil
+	// Create the email message
+	emailMessage, err := smtpclient.NewMessage(&emailConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return sendEmail(&emailConfig, emailMessage)
 }
 
 // Setup default values for flags, get the email config pointer.
-func setupDefaultEmailConfig(emailConfig *EmailConfig) {
+func setupDefaultEmailConfig(emailConfig *smtpclient.EmailConfig) {
 	// Set default values for subject and body if they are empty
 	if emailConfig.Subject == "" {
 		emailConfig.Subject = "GOMTP Test Subject"
 	}
-	if emailConfig.To == "" {
-		emailConfig.To = "to@example.com"
+	if len(emailConfig.To) == 0 {
+		emailConfig.To = smtpclient.AddressList{"to@example.com"}
 	}
-	if emailConfig.Body == "" {
+	if emailConfig.Body == "" && emailConfig.BodyHTML == "" {
 		emailConfig.Body = "This is the test email sent by gomtp."
 	}
 	if len(ccList) > 0 {
 		emailConfig.CcList = ccList
 	}
+	if len(bccList) > 0 {
+		emailConfig.BccList = bccList
+	}
 }
 
 func readBodyFromStdin() (string, error) {
@@ -127,7 +215,7 @@ func readBodyFromStdin() (string, error) {
 }
 
 // check --body, --body-file and stdio for email body
-func setBody(emailConfig *EmailConfig, stdioBody string) error {
+func setBody(emailConfig *smtpclient.EmailConfig, stdioBody string) error {
 	bodySourceCount := 0
 
 	if stdioBody != "" {
@@ -152,176 +240,201 @@ func setBody(emailConfig *EmailConfig, stdioBody string) error {
 	return nil
 }
 
+// check --body-html and --body-html-file for the html body
+func setBodyHTML(emailConfig *smtpclient.EmailConfig) error {
+	if emailBodyHTML != "" && emailBodyHTMLFile != "" {
+		return fmt.Errorf("cannot specify html body via multiple sources simultaneously")
+	}
+	if emailBodyHTML != "" {
+		emailConfig.BodyHTML = emailBodyHTML
+	}
+	if emailBodyHTMLFile != "" {
+		body, err := os.ReadFile(emailBodyHTMLFile)
+		if err != nil {
+			return err
+		}
+		emailConfig.BodyHTML = string(body)
+	}
+	return nil
+}
+
 // Set values from global flags
-func setFlags(emailConfig *EmailConfig) {
-	if emailTo != "" {
-		emailConfig.To = emailTo
+func setFlags(cmd *cobra.Command, emailConfig *smtpclient.EmailConfig) {
+	setConnectionFlags(cmd, emailConfig)
+	// An empty --to keeps the configured recipients
+	var to smtpclient.AddressList
+	for _, address := range emailTo {
+		if address != "" {
+			to = append(to, address)
+		}
+	}
+	if len(to) > 0 {
+		emailConfig.To = to
+	}
+	if envelopeFrom != "" {
+		emailConfig.EnvelopeFrom = envelopeFrom
+	}
+	if len(replyTo) > 0 {
+		emailConfig.ReplyTo = replyTo
+	}
+	if sender != "" {
+		emailConfig.Sender = sender
 	}
 	if emailSubject != "" {
 		emailConfig.Subject = emailSubject
 	}
+	if textFromHTML {
+		emailConfig.TextFromHTML = true
+	}
 }
 
-// Create email message from config
-func createEmailMessage(emailConfig *EmailConfig) *gomail.Message {
-	m := gomail.NewMessage()
-	m.SetHeader("From", emailConfig.From)
-	m.SetHeader("To", emailConfig.To)
-	m.SetHeader("Subject", emailConfig.Subject)
-	m.SetBody("text/plain", emailConfig.Body)
-	if len(emailConfig.CcList) > 0 {
-		m.SetHeader("Cc", emailConfig.CcList...)
-	}
-	return m
+// Apply the server, credential and sender flags. Boolean flags only apply when
+// given, so --ssl=false can turn off a configured ssl: true.
+func setConnectionFlags(cmd *cobra.Command, emailConfig *smtpclient.EmailConfig) {
+	if host != "" {
+		emailConfig.Host = host
+	}
+	if port != 0 {
+		emailConfig.Port = port
+	}
+	sslChanged, startTLSChanged := cmd.Flags().Changed("ssl"), cmd.Flags().Changed("starttls")
+	if sslChanged {
+		emailConfig.SSL = ssl
+	}
+	if startTLSChanged {
+		emailConfig.TLS = startTLS
+	}
+	// Picking one mode by flag turns off the configured other one
+	if sslChanged && ssl && !startTLSChanged {
+		emailConfig.TLS = false
+	}
+	if startTLSChanged && startTLS && !sslChanged {
+		emailConfig.SSL = false
+	}
+	if authMechanism != "" {
+		emailConfig.Auth = authMechanism
+	}
+	if username != "" {
+		emailConfig.Username = username
+	}
+	if from != "" {
+		emailConfig.From = from
+	}
+	if cmd.Flags().Changed("insecure") {
+		verify := !insecure
+		emailConfig.VerifyCertificate = &verify
+	}
+	if caFile != "" {
+		emailConfig.CAFile = caFile
+	}
+	if caDir != "" {
+		emailConfig.CADir = caDir
+	}
+	if clientCert != "" {
+		emailConfig.ClientCert = clientCert
+	}
+	if clientKey != "" {
+		emailConfig.ClientKey = clientKey
+	}
+	if tlsMinVersion != "" {
+		emailConfig.TLSMinVersion = tlsMinVersion
+	}
+	if tlsMaxVersion != "" {
+		emailConfig.TLSMaxVersion = tlsMaxVersion
+	}
+	if len(cipherSuites) > 0 {
+		emailConfig.CipherSuites = cipherSuites
+	}
+	if len(curvePreferences) > 0 {
+		emailConfig.CurvePreferences = curvePreferences
+	}
+	if len(pinSHA256) > 0 {
+		emailConfig.PinSHA256 = pinSHA256
+	}
 }
 
-func sendEmail(emailConfig *EmailConfig, m *gomail.Message) error {
-	// Validate mode selection
-	if emailConfig.SSL && emailConfig.TLS {
-		return fmt.Errorf("invalid configuration: both SSL and TLS (STARTTLS) are enabled; choose only one")
+// Send the message through a client built from the config.
+func sendEmail(emailConfig *smtpclient.EmailConfig, m *gomail.Message) (*smtpclient.Result, error) {
+	client, closeTrace, err := newClient(emailConfig)
+	if err != nil {
+		return nil, err
 	}
+	defer closeTrace()
 
-	// Render message to bytes once
-	var msgBuf bytes.Buffer
-	if _, err := m.WriteTo(&msgBuf); err != nil {
-		return err
+	ctx := context.Background()
+	if err := client.Dial(ctx); err != nil {
+		return client.Result(), err
 	}
+	defer client.Close()
 
-	// Common
-	addr := net.JoinHostPort(emailConfig.Host, strconv.Itoa(emailConfig.Port))
-	tlsConfig := &tls.Config{
-		ServerName:         emailConfig.Host,
-		InsecureSkipVerify: !emailConfig.VerifyCertificate,
-	}
+	err = client.Send(ctx, m)
+	return client.Result(), err
+}
 
+// Create a client writing the debug and trace output requested by the flags.
+// The returned func closes the trace file.
+func newClient(emailConfig *smtpclient.EmailConfig) (*smtpclient.Client, func(), error) {
+	client := smtpclient.NewClient(emailConfig)
 	if debug {
-		fmt.Fprintf(os.Stderr, "[gomtp][debug] host=%s port=%d ssl=%t starttls=%t auth=%s verifyCert=%t\n", emailConfig.Host, emailConfig.Port, emailConfig.SSL, emailConfig.TLS, emailConfig.Auth, emailConfig.VerifyCertificate)
+		client.Debug = os.Stderr
 	}
-
-	// Connect
-	var (
-		conn net.Conn
-		c    *smtp.Client
-		err  error
-	)
-
-	if emailConfig.SSL {
-		if debug {
-			fmt.Fprintf(os.Stderr, "[gomtp][debug] selected_mode=ssl_implicit\n")
-		}
-		conn, err = tls.Dial("tcp", addr, tlsConfig)
-		if err != nil {
-			return err
-		}
-		defer conn.Close()
-		// Log TLS parameters for implicit TLS
-		if debug {
-			if st := conn.(*tls.Conn).ConnectionState(); true {
-				fmt.Fprintf(os.Stderr, "[gomtp][debug] negotiated_tls=version:%x cipher_suite:%x server_name=%s\n", st.Version, st.CipherSuite, st.ServerName)
-				if len(st.PeerCertificates) > 0 {
-					cert := st.PeerCertificates[0]
-					fmt.Fprintf(os.Stderr, "[gomtp][debug] cert_subject=%s issuer=%s not_before=%s not_after=%s dns_names=%v\n",
-						cert.Subject.String(), cert.Issuer.String(), cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339), cert.DNSNames)
-				}
-			}
-		}
-		c, err = smtp.NewClient(conn, emailConfig.Host)
-		if err != nil {
-			return err
-		}
-	} else {
-		if emailConfig.TLS {
-			if debug {
-				fmt.Fprintf(os.Stderr, "[gomtp][debug] selected_mode=starttls\n")
-			}
-		} else {
-			if debug {
-				fmt.Fprintf(os.Stderr, "[gomtp][debug] selected_mode=plain_no_tls\n")
-			}
-		}
-		conn, err = net.Dial("tcp", addr)
+	client.TraceRedactBody = traceRedactBody
+	if traceFile != "" {
+		f, err := os.OpenFile(traceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
 		if err != nil {
-			return err
-		}
-		defer conn.Close()
-		c, err = smtp.NewClient(conn, emailConfig.Host)
-		if err != nil {
-			return err
+			return nil, nil, err
 		}
+		client.Trace = f
+		return client, func() { f.Close() }, nil
 	}
-	defer c.Quit()
-
-	// EHLO/HELO
-	if err := c.Hello(emailConfig.Host); err != nil {
-		return err
+	if trace {
+		client.Trace = os.Stderr
 	}
+	return client, func() {}, nil
+}
 
-	// STARTTLS if requested
-	if emailConfig.TLS {
-		if ok, _ := c.Extension("STARTTLS"); !ok {
-			return fmt.Errorf("server does not support STARTTLS")
-		}
-		if err := c.StartTLS(tlsConfig); err != nil {
-			return err
-		}
-		if debug {
-			if st, ok := c.TLSConnectionState(); ok {
-				fmt.Fprintf(os.Stderr, "[gomtp][debug] negotiated_tls=version:%x cipher_suite:%x server_name=%s\n", st.Version, st.CipherSuite, st.ServerName)
-				if len(st.PeerCertificates) > 0 {
-					cert := st.PeerCertificates[0]
-					fmt.Fprintf(os.Stderr, "[gomtp][debug] cert_subject=%s issuer=%s not_before=%s not_after=%s dns_names=%v\n",
-						cert.Subject.String(), cert.Issuer.String(), cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339), cert.DNSNames)
-				}
-			}
-		}
-	}
+// Add the server flags shared by the commands that connect to a server.
+func addConnectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&host, "host", "", "SMTP server host.")
+	cmd.Flags().IntVar(&port, "port", 0, "SMTP server port.")
+	cmd.Flags().BoolVar(&ssl, "ssl", false, "Use implicit TLS, usually on port 465.")
+	cmd.Flags().BoolVar(&startTLS, "starttls", false, "Upgrade the connection with STARTTLS, usually on port 587.")
+	cmd.Flags().StringVar(&authMechanism, "auth", "", "Authentication mechanism: AUTO | PLAIN | LOGIN | CRAM-MD5 | XOAUTH2 | OAUTHBEARER | NO.")
+	cmd.Flags().StringVar(&username, "user", "", "Username for authentication.")
+	cmd.Flags().StringVar(&from, "from", "", "From address, e.g. 'Ops Alerts <ops@example.com>'.")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "Skip verification of the server certificate.")
+	cmd.Flags().StringVar(&caFile, "ca-file", "", "PEM file of certificate authorities to trust in addition to the system ones.")
+	cmd.Flags().StringVar(&caDir, "ca-dir", "", "Directory of PEM certificate authorities to trust in addition to the system ones.")
+	cmd.Flags().StringVar(&clientCert, "client-cert", "", "PEM client certificate for mutual TLS. May also hold the key.")
+	cmd.Flags().StringVar(&clientKey, "client-key", "", "PEM private key of the client certificate.")
+	cmd.Flags().StringVar(&tlsMinVersion, "tls-min-version", "", "Lowest TLS version to negotiate: 1.0, 1.1, 1.2 or 1.3.")
+	cmd.Flags().StringVar(&tlsMaxVersion, "tls-max-version", "", "Highest TLS version to negotiate: 1.0, 1.1, 1.2 or 1.3.")
+	cmd.Flags().StringSliceVar(&cipherSuites, "cipher-suites", nil, "TLS 1.0-1.2 cipher suites to offer, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.")
+	cmd.Flags().StringSliceVar(&curvePreferences, "curve-preferences", nil, "Key exchange curves to offer: X25519, P-256, P-384 or P-521.")
+	cmd.Flags().StringSliceVar(&pinSHA256, "pin-sha256", nil, "Base64 SHA-256 hash of a public key the server certificate chain must contain. Can be repeated.")
+}
 
-	// AUTH if configured and supported
-	if emailConfig.Auth == "LOGIN" {
-		if ok, _ := c.Extension("AUTH"); ok {
-			a := smtp.PlainAuth("", emailConfig.Username, emailConfig.Password, emailConfig.Host)
-			if err := c.Auth(a); err != nil {
-				return err
-			}
-		} else if debug {
-			fmt.Fprintf(os.Stderr, "[gomtp][debug] server does not advertise AUTH; skipping auth\n")
-		}
-	}
+func addTraceFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&trace, "trace", false, "Print the SMTP conversation to stderr. AUTH payloads are redacted.")
+	cmd.Flags().StringVar(&traceFile, "trace-file", "", "Append the SMTP conversation to this file instead of stderr.")
+	cmd.Flags().BoolVar(&traceRedactBody, "trace-redact-body", false, "Replace the message data in the trace with its size.")
+}
 
-	// MAIL FROM
-	if err := c.Mail(emailConfig.From); err != nil {
-		return err
-	}
+// exitCertificateExpiry is the exit code when --warn-expiry finds a certificate about to expire.
+const exitCertificateExpiry = 3
 
-	// RCPT TO (To + Cc)
-	recipients := make([]string, 0, 1+len(emailConfig.CcList))
-	if emailConfig.To != "" {
-		recipients = append(recipients, emailConfig.To)
-	}
-	recipients = append(recipients, emailConfig.CcList...)
-	for _, rcpt := range recipients {
-		if rcpt == "" {
-			continue
-		}
-		if err := c.Rcpt(rcpt); err != nil {
-			return err
-		}
-	}
+// exitError makes Execute exit with code instead of 1.
+type exitError struct {
+	code int
+	err  error
+}
 
-	// DATA
-	wc, err := c.Data()
-	if err != nil {
-		return err
-	}
-	if _, err := wc.Write(msgBuf.Bytes()); err != nil {
-		_ = wc.Close()
-		return err
-	}
-	if err := wc.Close(); err != nil {
-		return err
-	}
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
 
-	return nil
+func (e *exitError) Unwrap() error {
+	return e.err
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -330,18 +443,40 @@ func Execute() {
 	rootCmd.SilenceUsage = true
 	err := rootCmd.Execute()
 	if err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.Flags().BoolP("help", "h", false, "Help menu.")
-	rootCmd.Flags().StringVarP(&gomtpYamlPath, "file", "f", "gomtp.yaml", "Configuration file path.")
-	rootCmd.Flags().StringVar(&emailTo, "to", "", "Target email address.")
+	addFileFlag(rootCmd)
+	rootCmd.Flags().StringVarP(&profileName, "profile", "P", "", "Profile of the configuration file to use.")
+	addConnectionFlags(rootCmd)
+	rootCmd.Flags().StringArrayVar(&emailTo, "to", []string{}, "Target email address, e.g. 'Ops Alerts <ops@example.com>'. Can be repeated.")
+	rootCmd.Flags().StringVar(&envelopeFrom, "envelope-from", "", "Envelope sender for MAIL FROM, e.g. a bounce address. Use '<>' for the null sender.")
+	rootCmd.Flags().StringArrayVar(&replyTo, "reply-to", []string{}, "Reply-To address. Can be repeated.")
+	rootCmd.Flags().StringVar(&sender, "sender", "", "Sender header address, when sending on behalf of the From address.")
 	rootCmd.Flags().StringVarP(&emailSubject, "subject", "s", "", "Subject of the email.")
 	rootCmd.Flags().StringVarP(&emailBody, "body", "b", "", "Body of the email.")
 	rootCmd.Flags().StringVar(&emailBodyFile, "body-file", "", "File that contains body of the email.")
+	rootCmd.Flags().StringVar(&emailBodyHTML, "body-html", "", "HTML body of the email. Sent as multipart/alternative together with a text body.")
+	rootCmd.Flags().StringVar(&emailBodyHTMLFile, "body-html-file", "", "File that contains the HTML body of the email.")
+	rootCmd.Flags().BoolVar(&textFromHTML, "text-from-html", false, "Generate the plain-text part from the HTML body when no text body is given.")
 	rootCmd.Flags().StringSliceVar(&ccList, "cc", []string{}, "CC email address")
+	rootCmd.Flags().StringSliceVar(&bccList, "bcc", []string{}, "BCC email address. Only added to the envelope, never to the headers.")
+	rootCmd.Flags().StringArrayVar(&templateVars, "var", []string{}, "Template variable as key=value for the subject and body. Can be repeated.")
+	rootCmd.Flags().StringVar(&varsFile, "vars-file", "", "JSON or YAML file with template variables.")
+	rootCmd.Flags().StringArrayVar(&headerList, "header", []string{}, "Custom header as 'Name: value', e.g. 'X-Priority: 1'. Can be repeated.")
+	rootCmd.Flags().BoolVar(&overrideHeaders, "override-headers", false, "Allow custom headers to replace From, To, Subject, Content-Type and other structural headers.")
+	rootCmd.Flags().StringArrayVar(&attachList, "attach", []string{}, "File to attach, as path[;name=...][;type=...][;inline]. Globs are expanded. Can be repeated.")
 	rootCmd.Flags().BoolVar(&debug, "debug", false, "Enable verbose SMTP/TLS debugging output.")
+	rootCmd.Flags().StringVar(&outputFormat, "output", "text", "Output format of the send result: text | json.")
+	addTraceFlags(rootCmd)
+	rootCmd.Flags().BoolVar(&timings, "timings", false, "Print how long each step of the SMTP transaction took.")
+	addWarnExpiryFlag(rootCmd)
 
 }
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"gomtp/smtpclient"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -17,9 +21,11 @@ type MailhogResponse struct {
 }
 
 type MailhogMessage struct {
+	ID      string           `json:"ID"`
 	From    MailhogAddress   `json:"From"`
 	To      []MailhogAddress `json:"To"`
 	Cc      []MailhogAddress `json:"Cc"`
+	Bcc     []MailhogAddress `json:"Bcc"`
 	Subject string           `json:"Subject"`
 	Snippet string           `json:"Snippet"`
 }
@@ -54,6 +60,46 @@ func getLatestMessageForRecipient(recipient string) (MailhogMessage, error) {
 	return MailhogMessage{}, nil
 }
 
+type MailpitMessageDetails struct {
+	ReturnPath  string              `json:"ReturnPath"`
+	ReplyTo     []MailhogAddress    `json:"ReplyTo"`
+	Text        string              `json:"Text"`
+	HTML        string              `json:"HTML"`
+	Attachments []MailpitAttachment `json:"Attachments"`
+	Inline      []MailpitAttachment `json:"Inline"`
+}
+
+type MailpitAttachment struct {
+	FileName    string `json:"FileName"`
+	ContentType string `json:"ContentType"`
+	ContentID   string `json:"ContentID"`
+	Size        int    `json:"Size"`
+}
+
+func getMessageDetails(id string) (MailpitMessageDetails, error) {
+	resp, err := http.Get("http://localhost:8025/api/v1/message/" + id)
+	if err != nil {
+		return MailpitMessageDetails{}, err
+	}
+	defer resp.Body.Close()
+
+	var details MailpitMessageDetails
+	err = json.NewDecoder(resp.Body).Decode(&details)
+	return details, err
+}
+
+func getMessageHeaders(id string) (map[string][]string, error) {
+	resp, err := http.Get("http://localhost:8025/api/v1/message/" + id + "/headers")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var headers map[string][]string
+	err = json.NewDecoder(resp.Body).Decode(&headers)
+	return headers, err
+}
+
 type TestGOMTPSuite struct {
 	suite.Suite
 	cmd cobra.Command
@@ -65,6 +111,8 @@ func TestGOMTPSuiteTest(t *testing.T) {
 
 func (suite *TestGOMTPSuite) SetupTest() {
 	suite.cmd = *rootCmd
+	// Repeatable flags append to the previous test's values unless reset
+	resetFlags()
 }
 
 func (suite *TestGOMTPSuite) TestHappyPath() {
@@ -560,11 +608,54 @@ func (suite *TestGOMTPSuite) TestSingleCcYaml() {
 
 func resetFlags() {
 	gomtpYamlPath = ""
-	emailTo = ""
+	profileName = ""
+	host = ""
+	port = 0
+	ssl = false
+	startTLS = false
+	authMechanism = ""
+	username = ""
+	from = ""
+	insecure = false
+	caFile = ""
+	caDir = ""
+	clientCert = ""
+	clientKey = ""
+	tlsMinVersion = ""
+	tlsMaxVersion = ""
+	cipherSuites = []string{}
+	curvePreferences = []string{}
+	pinSHA256 = []string{}
+	warnExpiry = ""
+	scanImplicitPort = 0
+	scanStartTLSPort = 0
+	probeLogin = false
+	// Changed would otherwise apply the boolean flags of a previous test
+	for _, command := range []*cobra.Command{rootCmd, probeCmd, tlsScanCmd} {
+		command.Flags().VisitAll(func(f *pflag.Flag) { f.Changed = false })
+	}
+	emailTo = []string{}
+	envelopeFrom = ""
+	replyTo = []string{}
+	sender = ""
 	emailSubject = ""
 	emailBody = ""
 	emailBodyFile = ""
 	ccList = []string{}
+	bccList = []string{}
+	outputFormat = "text"
+	timings = false
+	trace = false
+	traceFile = ""
+	traceRedactBody = false
+	attachList = []string{}
+	headerList = []string{}
+	overrideHeaders = false
+	emailBodyHTML = ""
+	emailBodyHTMLFile = ""
+	textFromHTML = false
+	templateVars = []string{}
+	varsFile = ""
 }
 
 func (suite *TestGOMTPSuite) TestMultiCcYaml() {
@@ -621,3 +712,767 @@ func (suite *TestGOMTPSuite) TestInvalidCcYaml() {
 	suite.Contains(b.String(), expected, "unexpected command output")
 
 }
+
+func (suite *TestGOMTPSuite) TestJSONOutput() {
+	resetFlags()
+	defer resetFlags()
+	to := "jsonoutput@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", to,
+		"--subject", "Test JSON Output",
+		"--body", "This is a test email for json output.",
+		"--cc", "jsonoutputcc@example.com",
+		"--output", "json",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+
+	var result smtpclient.Result
+	suite.NoError(json.Unmarshal(b.Bytes(), &result))
+	suite.True(result.Success)
+	suite.Equal(smtpclient.PhaseData, result.Phase)
+	suite.NotEmpty(result.QueueID)
+	suite.Len(result.Recipients, 2)
+	suite.Equal(to, result.Recipients[0].Address)
+	suite.True(result.Recipients[0].Accepted)
+	suite.Equal(250, result.Phases[len(result.Phases)-1].Reply.Code)
+}
+
+func (suite *TestGOMTPSuite) TestTimings() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", "timings@example.com",
+		"--subject", "Test Timings",
+		"--body", "This is a test email for timings.",
+		"--timings",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+
+	out := b.String()
+	suite.Contains(out, "STEP")
+	for _, step := range []string{"dns", "tcp", "greeting", "ehlo", "mail", "rcpt", "data", "total"} {
+		suite.Regexp(`(?m)^`+step+` `, out)
+	}
+	suite.Contains(out, "timings@example.com")
+	suite.Regexp(`Email sent successfully!$`, out)
+}
+
+func (suite *TestGOMTPSuite) TestTraceFile() {
+	resetFlags()
+	defer resetFlags()
+	traceFilePath := filepath.Join(suite.T().TempDir(), "trace.log")
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithAuth.yaml",
+		"--to", "trace@example.com",
+		"--subject", "Test Trace",
+		"--body", "This is a test email for the trace.",
+		"--trace-file", traceFilePath,
+		"--trace-redact-body",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	content, err := os.ReadFile(traceFilePath)
+	suite.NoError(err)
+	traceLog := string(content)
+	suite.Contains(traceLog, "S: 220 ")
+	suite.Contains(traceLog, "C: RCPT TO:<trace@example.com>")
+	suite.Contains(traceLog, " <redacted>")
+	suite.Contains(traceLog, "bytes of message data redacted>")
+	suite.NotContains(traceLog, "This is a test email for the trace.")
+}
+
+func (suite *TestGOMTPSuite) TestAttachFlag() {
+	resetFlags()
+	defer resetFlags()
+	to := "attachflag@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", to,
+		"--subject", "Test Attach Flag",
+		"--attach", "../tests/attachments/report.txt;name=nightly.txt",
+		"--attach", "../tests/attachments/logo.png;inline",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+
+	expected := "Attaching ../tests/attachments/report.txt as nightly.txt (38 B, text/plain; charset=utf-8)\n" +
+		"Embedding ../tests/attachments/logo.png as cid:logo.png (69 B, image/png)\n" +
+		"Email sent successfully!"
+	suite.Equal(expected, b.String())
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Equal("Test Attach Flag", latestMessage.Subject)
+	details, err := getMessageDetails(latestMessage.ID)
+	suite.NoError(err)
+	suite.Len(details.Attachments, 1)
+	suite.Equal("nightly.txt", details.Attachments[0].FileName)
+	suite.Equal(38, details.Attachments[0].Size)
+	suite.Len(details.Inline, 1)
+	suite.Equal("logo.png", details.Inline[0].ContentID)
+	suite.Equal("image/png", details.Inline[0].ContentType)
+}
+
+func (suite *TestGOMTPSuite) TestAttachmentsYaml() {
+	resetFlags()
+	to := "attachmentsyaml@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithAttachments.yaml",
+		"--to", to,
+		"--subject", "Test Attachments Yaml",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Contains(b.String(), "Attaching ../tests/attachments/backup-2.log as backup-2.log (12 B, ")
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	details, err := getMessageDetails(latestMessage.ID)
+	suite.NoError(err)
+	var names []string
+	for _, attachment := range details.Attachments {
+		names = append(names, attachment.FileName)
+	}
+	suite.Equal([]string{"report.txt", "backup-1.log", "backup-2.log"}, names)
+	suite.Len(details.Inline, 1)
+}
+
+func (suite *TestGOMTPSuite) TestAttachFlagMissingFile() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--attach", "../tests/attachments/missing.pdf",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.Error(err)
+	suite.Contains(b.String(), "attachment: stat ../tests/attachments/missing.pdf: no such file or directory")
+}
+
+func (suite *TestGOMTPSuite) TestBodyHTMLFlag() {
+	resetFlags()
+	defer resetFlags()
+	to := "bodyhtmlflag@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithNoBody.yaml",
+		"--to", to,
+		"--subject", "Body HTML Flag",
+		"--body", "This is the text part.",
+		"--body-html", "<p>This is the <b>HTML</b> part.</p>",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	details, err := getMessageDetails(latestMessage.ID)
+	suite.NoError(err)
+	suite.Equal("This is the text part.", details.Text)
+	suite.Equal("<p>This is the <b>HTML</b> part.</p>", details.HTML)
+}
+
+func (suite *TestGOMTPSuite) TestBodyHTMLFileFlagWithTextFromHTML() {
+	resetFlags()
+	defer resetFlags()
+	to := "bodyhtmlfileflag@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithNoBody.yaml",
+		"--to", to,
+		"--subject", "Body HTML File Flag",
+		"--body-html-file", "../tests/gomtpYamls/emailBodyFile.html",
+		"--text-from-html",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	details, err := getMessageDetails(latestMessage.ID)
+	suite.NoError(err)
+	suite.Equal("Backup Report\n\nAll backups completed.", details.Text)
+	suite.Contains(details.HTML, "<h1>Backup Report</h1>")
+}
+
+func (suite *TestGOMTPSuite) TestBodyHTMLYaml() {
+	resetFlags()
+	to := "bodyhtmlyaml@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithHtmlBody.yaml",
+		"--to", to,
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	headers, err := getMessageHeaders(latestMessage.ID)
+	suite.NoError(err)
+	suite.Equal([]string{"text/html; charset=UTF-8"}, headers["Content-Type"], "the default text body must not be added to an html only email")
+	details, err := getMessageDetails(latestMessage.ID)
+	suite.NoError(err)
+	suite.Equal("<p>this is <b>line 1</b></p>\n", details.HTML)
+}
+
+func (suite *TestGOMTPSuite) TestBodyHTMLAndBodyHTMLFileFlag() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithNoBody.yaml",
+		"--body-html", "<p>should fail</p>",
+		"--body-html-file", "../tests/gomtpYamls/emailBodyFile.html",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.Error(err)
+	suite.Contains(b.String(), "cannot specify html body via multiple sources simultaneously")
+}
+
+func (suite *TestGOMTPSuite) TestTemplateVars() {
+	resetFlags()
+	defer resetFlags()
+	to := "templatevars@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithVars.yaml",
+		"--to", to,
+		"--vars-file", "../tests/vars/vars.yaml",
+		"--var", "status=down",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Equal("[down] checkout", latestMessage.Subject)
+	suite.Equal("Service checkout on db-1 is down.", latestMessage.Snippet)
+}
+
+func (suite *TestGOMTPSuite) TestBodyWithBracesWithoutVars() {
+	resetFlags()
+	defer resetFlags()
+	to := "bodywithbraces@example.com"
+	body := "backup failed: {{ braces }} in line 3"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", to,
+		"--subject", "Test {{ braces }}",
+		"--body", body,
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Equal("Test {{ braces }}", latestMessage.Subject)
+	suite.Equal(body, latestMessage.Snippet)
+}
+
+func (suite *TestGOMTPSuite) TestTemplateError() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithVars.yaml",
+		"--to", "templateerror@example.com",
+		"--timings",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.Error(err)
+	suite.Contains(b.String(), `template: body:1:31: executing "body" at <.host.name>: map has no entry for key "host"`)
+	suite.NotContains(b.String(), "STEP", "no connection is opened")
+}
+
+func (suite *TestGOMTPSuite) TestBccFlag() {
+	resetFlags()
+	defer resetFlags()
+	to := "bccflagtarget@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", to,
+		"--subject", "Test Bcc Flag",
+		"--body", "This is a test email with bcc.",
+		"--cc", "bccflagcc@example.com",
+		"--bcc", "bccflag1@example.com,bccflag2@example.com",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Equal("Test Bcc Flag", latestMessage.Subject)
+	suite.Equal("bccflagcc@example.com", latestMessage.Cc[0].Address)
+	suite.Len(latestMessage.Bcc, 2, "bcc recipients receive the email")
+	suite.Equal("bccflag1@example.com", latestMessage.Bcc[0].Address)
+	suite.Equal("bccflag2@example.com", latestMessage.Bcc[1].Address)
+
+	headers, err := getMessageHeaders(latestMessage.ID)
+	suite.NoError(err)
+	suite.NotContains(headers, "Bcc")
+	for name, values := range headers {
+		for _, value := range values {
+			suite.NotContains(value, "bccflag1@example.com", name)
+			suite.NotContains(value, "bccflag2@example.com", name)
+		}
+	}
+}
+
+func (suite *TestGOMTPSuite) TestBccYaml() {
+	resetFlags()
+	to := "bccyamltarget@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithBcc.yaml",
+		"--to", to,
+		"--subject", "Test Bcc Yaml",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Len(latestMessage.Bcc, 2)
+	suite.Equal("bccyaml1@example.com", latestMessage.Bcc[0].Address)
+
+	headers, err := getMessageHeaders(latestMessage.ID)
+	suite.NoError(err)
+	suite.NotContains(headers, "Bcc")
+}
+
+func (suite *TestGOMTPSuite) TestMultipleToFlag() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", "Ops Alerts <multitoflag1@example.com>",
+		"--to", "multitoflag2@example.com",
+		"--subject", "Test Multiple To Flag",
+		"--body", "This is a test email with multiple to addresses.",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient("multitoflag1@example.com")
+	suite.NoError(err)
+	suite.Equal("Test Multiple To Flag", latestMessage.Subject)
+	suite.Len(latestMessage.To, 2)
+	suite.Equal("Ops Alerts", latestMessage.To[0].Name)
+	suite.Equal("multitoflag2@example.com", latestMessage.To[1].Address)
+	suite.Empty(latestMessage.Bcc)
+}
+
+func (suite *TestGOMTPSuite) TestMultipleToYaml() {
+	resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithMultiTo.yaml",
+		"--subject", "Test Multiple To Yaml",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+
+	latestMessage, err := getLatestMessageForRecipient("multitoyaml1@example.com")
+	suite.NoError(err)
+	suite.Equal("Test Multiple To Yaml", latestMessage.Subject)
+	suite.Equal("Gomtp Bot", latestMessage.From.Name)
+	suite.Equal("from@example.com", latestMessage.From.Address)
+	suite.Len(latestMessage.To, 2)
+	suite.Equal("Ops Alerts", latestMessage.To[0].Name)
+	suite.Equal("multitoyaml2@example.com", latestMessage.To[1].Address)
+}
+
+func (suite *TestGOMTPSuite) TestInvalidToFlag() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", "invalid.example.com",
+		"--timings",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.Error(err)
+	suite.Contains(b.String(), `invalid to address "invalid.example.com": mail: missing '@' or angle-addr`)
+	suite.NotContains(b.String(), "STEP", "no connection is opened")
+}
+
+func (suite *TestGOMTPSuite) TestEnvelopeFromReplyToSenderFlags() {
+	resetFlags()
+	defer resetFlags()
+	to := "envelopefrom@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", to,
+		"--subject", "Test Envelope From",
+		"--envelope-from", "bounces+envelopefrom=example.com@example.com",
+		"--reply-to", "Support <support@example.com>",
+		"--sender", "relay@example.com",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Equal("from@example.com", latestMessage.From.Address, "the header From is unchanged")
+	details, err := getMessageDetails(latestMessage.ID)
+	suite.NoError(err)
+	suite.Equal("bounces+envelopefrom=example.com@example.com", details.ReturnPath)
+	suite.Len(details.ReplyTo, 1)
+	suite.Equal("Support", details.ReplyTo[0].Name)
+	suite.Equal("support@example.com", details.ReplyTo[0].Address)
+	headers, err := getMessageHeaders(latestMessage.ID)
+	suite.NoError(err)
+	suite.Equal([]string{"relay@example.com"}, headers["Sender"])
+}
+
+func (suite *TestGOMTPSuite) TestNullEnvelopeFrom() {
+	resetFlags()
+	defer resetFlags()
+	to := "nullenvelopefrom@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", to,
+		"--subject", "Test Null Envelope From",
+		"--envelope-from", "<>",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Equal("Test Null Envelope From", latestMessage.Subject)
+	suite.Equal("from@example.com", latestMessage.From.Address)
+	details, err := getMessageDetails(latestMessage.ID)
+	suite.NoError(err)
+	suite.Empty(details.ReturnPath)
+}
+
+func (suite *TestGOMTPSuite) TestHeaders() {
+	resetFlags()
+	defer resetFlags()
+	to := "headers@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithHeaders.yaml",
+		"--to", to,
+		"--subject", "Test Headers",
+		"--header", "X-Campaign: summer",
+		"--header", "Message-ID: <headers-test@example.com>",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	headers, err := getMessageHeaders(latestMessage.ID)
+	suite.NoError(err)
+	suite.Equal([]string{"1"}, headers["X-Priority"])
+	suite.Equal([]string{"<mailto:unsubscribe@example.com>"}, headers["List-Unsubscribe"])
+	suite.Equal([]string{"summer"}, headers["X-Campaign"], "--header replaces the yaml value")
+	suite.Equal([]string{"<headers-test@example.com>"}, headers["Message-Id"])
+}
+
+func (suite *TestGOMTPSuite) TestStructuralHeaderNeedsOverride() {
+	resetFlags()
+	defer resetFlags()
+	to := "structuralheader@example.com"
+	args := []string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--to", to,
+		"--header", "Subject: Overridden Subject",
+	}
+	suite.cmd.SetArgs(args)
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.Error(err)
+	suite.Contains(b.String(), `invalid header "Subject": it is set by gomtp; enable overrideHeaders to replace it`)
+
+	suite.cmd.SetArgs(append(args, "--override-headers"))
+	b.Reset()
+	err = suite.cmd.Execute()
+	suite.NoError(err)
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Equal("Overridden Subject", latestMessage.Subject)
+}
+
+func (suite *TestGOMTPSuite) TestProfileFlag() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithProfiles.yaml",
+		"-P", "relay-eu",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient("relay-eu@example.com")
+	suite.NoError(err)
+	suite.Equal("Relay EU test", latestMessage.Subject)
+	suite.Equal("relay-eu@example.com", latestMessage.From.Address)
+}
+
+func (suite *TestGOMTPSuite) TestProfileFlagNotFound() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfigurationWithProfiles.yaml",
+		"--profile", "relay-us",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.Error(err)
+	suite.Contains(b.String(), `profile "relay-us" not found`)
+}
+
+func (suite *TestGOMTPSuite) TestEnvWithoutConfigFile() {
+	resetFlags()
+	defer resetFlags()
+	isolateConfigSearch(suite.T())
+	to := "envonly@example.com"
+	suite.T().Setenv("GOMTP_HOST", "127.0.0.1")
+	suite.T().Setenv("GOMTP_PORT", "1025")
+	suite.T().Setenv("GOMTP_AUTH", "NO")
+	suite.T().Setenv("GOMTP_FROM", "env@example.com")
+	suite.T().Setenv("GOMTP_TO", "ignored@example.com")
+	suite.T().Setenv("GOMTP_SUBJECT", "Env Subject")
+	suite.cmd.SetArgs([]string{
+		"--to", to,
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Equal("Env Subject", latestMessage.Subject)
+	suite.Equal("env@example.com", latestMessage.From.Address)
+	suite.Equal([]MailhogAddress{{Address: to}}, latestMessage.To, "--to overrides GOMTP_TO")
+}
+
+func (suite *TestGOMTPSuite) TestConnectionFlagsWithoutConfigFile() {
+	resetFlags()
+	defer resetFlags()
+	isolateConfigSearch(suite.T())
+	to := "connectionflags@example.com"
+	suite.cmd.SetArgs([]string{
+		"--host", "127.0.0.1",
+		"--port", "1025",
+		"--auth", "NO",
+		"--from", "Flags <flags@example.com>",
+		"--to", to,
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+
+	latestMessage, err := getLatestMessageForRecipient(to)
+	suite.NoError(err)
+	suite.Equal("flags@example.com", latestMessage.From.Address)
+}
+
+func (suite *TestGOMTPSuite) TestSSLFlagOverridesYaml() {
+	resetFlags()
+	defer resetFlags()
+	to := "sslflag@example.com"
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/nonSslServerWithSslConfiguration.yaml",
+		"--ssl=false",
+		"--to", to,
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.NoError(err)
+	suite.Equal("Email sent successfully!", b.String())
+}
+
+func (suite *TestGOMTPSuite) TestSetConnectionFlags() {
+	resetFlags()
+	defer resetFlags()
+	emailConfig := smtpclient.EmailConfig{Host: "smtp.example.com", Port: 465, SSL: true, Username: "user"}
+
+	setConnectionFlags(&suite.cmd, &emailConfig)
+	suite.Equal(smtpclient.EmailConfig{Host: "smtp.example.com", Port: 465, SSL: true, Username: "user"}, emailConfig, "unset flags keep the configuration")
+
+	suite.NoError(suite.cmd.ParseFlags([]string{"--port", "587", "--ssl=false", "--starttls", "--insecure", "--user", "other", "--auth", "LOGIN",
+		"--ca-file", "ca.pem", "--ca-dir", "certs", "--client-cert", "client.crt", "--client-key", "client.key",
+		"--tls-min-version", "1.2", "--tls-max-version", "1.3", "--cipher-suites", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", "--curve-preferences", "X25519",
+		"--pin-sha256", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}))
+	setConnectionFlags(&suite.cmd, &emailConfig)
+	suite.Equal(smtpclient.EmailConfig{Host: "smtp.example.com", Port: 587, TLS: true, Auth: "LOGIN", Username: "other", VerifyCertificate: new(bool),
+		CAFile: "ca.pem", CADir: "certs", ClientCert: "client.crt", ClientKey: "client.key",
+		TLSMinVersion: "1.2", TLSMaxVersion: "1.3", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}, CurvePreferences: []string{"X25519"},
+		PinSHA256: []string{"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}, emailConfig)
+}
+
+func (suite *TestGOMTPSuite) TestTransportFlagsReplaceConfiguredMode() {
+	tests := []struct {
+		flags    []string
+		config   smtpclient.EmailConfig
+		expected smtpclient.EmailConfig
+	}{
+		{[]string{"--ssl"}, smtpclient.EmailConfig{TLS: true}, smtpclient.EmailConfig{SSL: true}},
+		{[]string{"--starttls"}, smtpclient.EmailConfig{SSL: true}, smtpclient.EmailConfig{TLS: true}},
+		{[]string{"--ssl=false"}, smtpclient.EmailConfig{SSL: true, TLS: true}, smtpclient.EmailConfig{TLS: true}},
+		{[]string{"--ssl", "--starttls"}, smtpclient.EmailConfig{}, smtpclient.EmailConfig{SSL: true, TLS: true}},
+	}
+	for _, tt := range tests {
+		resetFlags()
+		suite.NoError(suite.cmd.ParseFlags(tt.flags))
+		emailConfig := tt.config
+		setConnectionFlags(&suite.cmd, &emailConfig)
+		suite.Equal(tt.expected, emailConfig, tt.flags)
+	}
+	resetFlags()
+}
+
+func (suite *TestGOMTPSuite) TestJSONOutputError() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/nonSslServerWithSslConfiguration.yaml",
+		"--output", "json",
+	})
+
+	stdout := bytes.NewBufferString("")
+	stderr := bytes.NewBufferString("")
+	suite.cmd.SetOut(stdout)
+	suite.cmd.SetErr(stderr)
+	err := suite.cmd.Execute()
+	suite.Error(err)
+
+	// Only the JSON document is relevant; cobra prints the usage after it in tests
+	var result smtpclient.Result
+	suite.NoError(json.NewDecoder(stdout).Decode(&result))
+	suite.False(result.Success)
+	suite.Equal(smtpclient.PhaseConnect, result.Phase)
+	suite.Contains(result.Error, "tls: first record does not look like a TLS handshake")
+}
+
+func (suite *TestGOMTPSuite) TestInvalidOutputFormat() {
+	resetFlags()
+	defer resetFlags()
+	suite.cmd.SetArgs([]string{
+		"--file", "../tests/gomtpYamls/successConfiguration.yaml",
+		"--output", "xml",
+	})
+
+	b := bytes.NewBufferString("")
+	suite.cmd.SetOut(b)
+	suite.cmd.SetErr(b)
+	err := suite.cmd.Execute()
+	suite.Error(err)
+	suite.Contains(b.String(), "output can be one of these: text | json")
+}
@@ -20,6 +20,9 @@ var yandexGomtpYamlTmpl []byte
 //go:embed embeddedFiles/brevo.yaml
 var brevoGomtpYamlTmpl []byte
 
+//go:embed embeddedFiles/office365.yaml
+var office365GomtpYamlTmpl []byte
+
 var (
 	gomtpTemplatePath string
 	providerName      string
@@ -31,6 +34,7 @@ const templateUsageMessage = `Example commands:
   gomtp template -p gmail # Create a file named gomtp.yaml filled with configuration for gmail.
   gomtp template -p yandex # Create a file named gomtp.yaml filled with configuration for gmail.
   gomtp template -p brevo # Create a file named gomtp.yaml filled with configuration for brevo.
+  gomtp template -p office365 # Create a file named gomtp.yaml filled with OAuth2 configuration for office365.
   gomtp template -p brevo -o custom.yaml # Create a file named custom.yaml filled with configuration for gmail.
 `
 
@@ -64,8 +68,10 @@ func checkProvider(providerName string) ([]byte, error) {
 		return yandexGomtpYamlTmpl, nil
 	case "brevo":
 		return brevoGomtpYamlTmpl, nil
+	case "office365":
+		return office365GomtpYamlTmpl, nil
 	default:
-		return nil, errors.New("provider can be one of these: mailhog | gmail | yandex | brevo")
+		return nil, errors.New("provider can be one of these: mailhog | gmail | yandex | brevo | office365")
 	}
 }
 
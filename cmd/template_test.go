@@ -136,6 +136,30 @@ func TestBrevoTemplateProvider(t *testing.T) {
 	os.Remove(customYamlPath)
 }
 
+func TestOffice365TemplateProvider(t *testing.T) {
+	command := rootCmd
+	customYamlPath := "./test.yaml"
+	command.SetArgs([]string{
+		"template",
+		"--output", customYamlPath,
+		"--provider", "office365",
+	})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.Nil(t, err)
+	data, err := os.ReadFile(customYamlPath)
+	fileContent := string(data)
+	assert.Nil(t, err)
+	assert.Contains(t, fileContent, "host: 'smtp.office365.com'")
+	assert.Contains(t, fileContent, "port: 587")
+	assert.Contains(t, fileContent, "tls: true")
+	assert.Contains(t, fileContent, "auth: 'XOAUTH2'")
+	assert.Contains(t, fileContent, "tokenUrl: 'https://login.microsoftonline.com/common/oauth2/v2.0/token'")
+	os.Remove(customYamlPath)
+}
+
 func TestInvalidTemplateProvider(t *testing.T) {
 	command := rootCmd
 	customYamlPath := "./test.yaml"
@@ -156,3 +180,13 @@ func TestInvalidTemplateProvider(t *testing.T) {
 func TestPermissionErrorPathTemplateCommand(t *testing.T) {
 
 }
+
+func TestTemplatesAreValid(t *testing.T) {
+	for _, provider := range []string{"mailhog", "gmail", "yandex", "brevo", "office365"} {
+		template, err := checkProvider(provider)
+		assert.Nil(t, err)
+		problems, err := checkConfig(writeConfig(t, string(template)))
+		assert.Nil(t, err)
+		assert.Empty(t, problems, provider)
+	}
+}
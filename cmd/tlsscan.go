@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"gomtp/smtpclient"
+
+	"github.com/spf13/cobra"
+)
+
+var scanImplicitPort int
+var scanStartTLSPort int
+
+const tlsScanUsageMessage = `Connect to the configured SMTP server once per TLS version and cipher suite and report which
+ones it accepts. Deprecated protocols (TLS 1.0 and 1.1) and weak cipher suites lower the grade.
+
+Example commands:
+  gomtp tls-scan # Scan the server configured in gomtp.yaml with its ssl or tls setting.
+  gomtp tls-scan --host smtp.example.com --implicit-port 465 --starttls-port 587 # Scan both modes.
+  gomtp tls-scan -P relay-eu --output json # Scan the server of the relay-eu profile and print JSON.
+`
+
+var tlsScanCmd = &cobra.Command{
+	Use:   "tls-scan",
+	Short: "Report the TLS versions and cipher suites an SMTP server accepts.",
+	Long:  tlsScanUsageMessage,
+	Args:  cobra.NoArgs,
+	RunE:  tlsScanRun,
+}
+
+func tlsScanRun(cmd *cobra.Command, args []string) error {
+	if outputFormat != "text" && outputFormat != "json" {
+		return fmt.Errorf("output can be one of these: text | json")
+	}
+	emailConfig, err := loadEmailConfig(gomtpYamlPath, profileName)
+	if err != nil {
+		return err
+	}
+	setConnectionFlags(cmd, &emailConfig)
+	targets := scanTargets(emailConfig)
+	for i := range targets {
+		if err := validateConfig(&targets[i], false); err != nil {
+			return err
+		}
+	}
+
+	scans := []*smtpclient.TLSScan{}
+	for _, target := range targets {
+		scan, err := smtpclient.ScanTLS(context.Background(), &target)
+		if err != nil {
+			return fmt.Errorf("%w; use --ssl, --starttls, --implicit-port or --starttls-port", err)
+		}
+		scans = append(scans, scan)
+	}
+
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(scans); err != nil {
+			return err
+		}
+	} else if err := printTLSScans(cmd.OutOrStdout(), scans); err != nil {
+		return err
+	}
+
+	for _, scan := range scans {
+		if scan.Error != "" {
+			return fmt.Errorf("could not scan %s:%d: %s", scan.Host, scan.Port, scan.Error)
+		}
+	}
+	return nil
+}
+
+// Return a config per mode given by --implicit-port and --starttls-port, or the configured one.
+func scanTargets(emailConfig smtpclient.EmailConfig) []smtpclient.EmailConfig {
+	if scanImplicitPort == 0 && scanStartTLSPort == 0 {
+		return []smtpclient.EmailConfig{emailConfig}
+	}
+	var targets []smtpclient.EmailConfig
+	if scanImplicitPort != 0 {
+		target := emailConfig
+		target.Port, target.SSL, target.TLS = scanImplicitPort, true, false
+		targets = append(targets, target)
+	}
+	if scanStartTLSPort != 0 {
+		target := emailConfig
+		target.Port, target.SSL, target.TLS = scanStartTLSPort, false, true
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// Write a table per scan with a row for every accepted cipher suite and every rejected version.
+func printTLSScans(w io.Writer, scans []*smtpclient.TLSScan) error {
+	for i, scan := range scans {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		mode := "STARTTLS"
+		if scan.Mode == smtpclient.ScanImplicitTLS {
+			mode = "implicit TLS"
+		}
+		if scan.Error != "" {
+			fmt.Fprintf(w, "%s:%d (%s): error: %s\n", scan.Host, scan.Port, mode, scan.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%s:%d (%s): grade %s\n", scan.Host, scan.Port, mode, scan.Grade)
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tCIPHER SUITE\tGRADE\tNOTE")
+		for _, version := range scan.Versions {
+			if !version.Accepted {
+				fmt.Fprintf(tw, "%s\t-\t-\trejected\n", version.Version)
+				continue
+			}
+			for _, suite := range version.CipherSuites {
+				if !suite.Accepted {
+					continue
+				}
+				grade, note := suite.Grade, suite.Weakness
+				if version.Deprecated {
+					grade, note = version.Grade, "deprecated protocol"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", version.Version, suite.Name, grade, note)
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(tlsScanCmd)
+	addFileFlag(tlsScanCmd)
+	tlsScanCmd.Flags().StringVarP(&profileName, "profile", "P", "", "Profile of the configuration file to use.")
+	addConnectionFlags(tlsScanCmd)
+	tlsScanCmd.Flags().IntVar(&scanImplicitPort, "implicit-port", 0, "Scan this port with implicit TLS instead of the configured port and mode.")
+	tlsScanCmd.Flags().IntVar(&scanStartTLSPort, "starttls-port", 0, "Scan this port with STARTTLS instead of the configured port and mode.")
+	tlsScanCmd.Flags().StringVar(&outputFormat, "output", "text", "Output format of the scan: text | json.")
+}
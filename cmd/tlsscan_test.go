@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"gomtp/smtpclient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSScanCommandNeedsTLS(t *testing.T) {
+	resetFlags()
+	defer resetFlags()
+	isolateConfigSearch(t)
+	command := rootCmd
+	command.SetArgs([]string{"tls-scan", "--host", "127.0.0.1", "--port", "1025"})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.EqualError(t, err, "tls-scan needs ssl or tls to be enabled; use --ssl, --starttls, --implicit-port or --starttls-port")
+}
+
+func TestTLSScanCommandHandshakeFails(t *testing.T) {
+	resetFlags()
+	defer resetFlags()
+	isolateConfigSearch(t)
+	command := rootCmd
+	command.SetArgs([]string{"tls-scan", "--host", "127.0.0.1", "--implicit-port", "1025", "--output", "json"})
+	b := bytes.NewBufferString("")
+	command.SetOut(b)
+	command.SetErr(b)
+	err := command.Execute()
+	assert.ErrorContains(t, err, "could not scan 127.0.0.1:1025: ")
+
+	var scans []smtpclient.TLSScan
+	require.NoError(t, json.NewDecoder(b).Decode(&scans))
+	require.Len(t, scans, 1)
+	assert.Equal(t, smtpclient.ScanImplicitTLS, scans[0].Mode)
+	assert.Contains(t, scans[0].Error, "tls: first record does not look like a TLS handshake")
+}
+
+func TestScanTargets(t *testing.T) {
+	resetFlags()
+	defer resetFlags()
+	emailConfig := smtpclient.EmailConfig{Host: "smtp.example.com", Port: 25, TLS: true}
+	assert.Equal(t, []smtpclient.EmailConfig{emailConfig}, scanTargets(emailConfig))
+
+	scanImplicitPort, scanStartTLSPort = 465, 587
+	assert.Equal(t, []smtpclient.EmailConfig{
+		{Host: "smtp.example.com", Port: 465, SSL: true},
+		{Host: "smtp.example.com", Port: 587, TLS: true},
+	}, scanTargets(emailConfig))
+}
+
+func TestPrintTLSScans(t *testing.T) {
+	scans := []*smtpclient.TLSScan{
+		{Host: "smtp.example.com", Port: 587, Mode: smtpclient.ScanStartTLS, Grade: "F", Versions: []smtpclient.TLSVersionScan{
+			{Version: "TLS 1.0", Accepted: true, Deprecated: true, Grade: "F", CipherSuites: []smtpclient.CipherSuiteScan{
+				{Name: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", Accepted: true, Grade: "B", Weakness: "CBC mode"},
+				{Name: "TLS_RSA_WITH_RC4_128_SHA", Grade: "F", Weakness: "RC4 is broken"},
+			}},
+			{Version: "TLS 1.1"},
+			{Version: "TLS 1.2", Accepted: true, Grade: "B", CipherSuites: []smtpclient.CipherSuiteScan{
+				{Name: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", Accepted: true, Grade: "A"},
+				{Name: "TLS_RSA_WITH_AES_128_GCM_SHA256", Accepted: true, Grade: "B", Weakness: "no forward secrecy"},
+			}},
+		}},
+		{Host: "smtp.example.com", Port: 465, Mode: smtpclient.ScanImplicitTLS, Error: "connection refused"},
+	}
+	var b bytes.Buffer
+	require.NoError(t, printTLSScans(&b, scans))
+	assert.Equal(t, `smtp.example.com:587 (STARTTLS): grade F
+VERSION  CIPHER SUITE                           GRADE  NOTE
+TLS 1.0  TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA     F      deprecated protocol
+TLS 1.1  -                                      -      rejected
+TLS 1.2  TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256  A      
+TLS 1.2  TLS_RSA_WITH_AES_128_GCM_SHA256        B      no forward secrecy
+
+smtp.example.com:465 (implicit TLS): error: connection refused
+`, b.String())
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gomtp/smtpclient"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configProblem is a problem found by config validate. line is 0 when the
+// problem is not on a line of the file, e.g. a missing key.
+type configProblem struct {
+	location string
+	line     int
+	message  string
+}
+
+func (p configProblem) String() string {
+	return p.location + ": " + p.message
+}
+
+var problemLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Check a configuration file together with the GOMTP_* environment variables and
+// return every problem in line order. A file with profiles is checked profile by profile.
+func checkConfig(path string) ([]configProblem, error) {
+	configFile, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var problems []configProblem
+	seen := map[string]bool{}
+	add := func(line int, location, message string) {
+		p := configProblem{location: location, line: line, message: message}
+		if line > 0 {
+			p.location = path + ":" + strconv.Itoa(line)
+		}
+		if !seen[p.String()] {
+			seen[p.String()] = true
+			problems = append(problems, p)
+		}
+	}
+	addYAMLError := func(err error) {
+		messages := []string{err.Error()}
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			messages = typeErr.Errors
+		}
+		for _, message := range messages {
+			if m := problemLinePattern.FindStringSubmatch(message); m != nil {
+				line, _ := strconv.Atoi(m[1])
+				add(line, "", m[2])
+			} else {
+				add(0, path, message)
+			}
+		}
+	}
+
+	// A syntax error stops the parser, so it is the only problem reported
+	var document yaml.Node
+	if err := yaml.Unmarshal(configFile, &document); err != nil {
+		addYAMLError(err)
+		return problems, nil
+	}
+
+	keys := topLevelKeys(&document)
+	hasProfiles := false
+	for _, key := range keys {
+		hasProfiles = hasProfiles || key.Value == "profiles"
+	}
+	// The sections of every profile, by name; a flat file is a single unnamed profile
+	type sections struct{ defaults, profile *yaml.Node }
+	profiles := map[string]sections{"": {&yaml.Node{}, &document}}
+	if hasProfiles {
+		for _, key := range keys {
+			if key.Value != "profiles" && key.Value != "defaults" {
+				add(key.Line, "", fmt.Sprintf("move %q under defaults or a profile; a file with profiles only has defaults and profiles at the top level", key.Value))
+			}
+		}
+		if err := decodeStrict(configFile, &profilesConfig{}); err != nil {
+			addYAMLError(err)
+		}
+		var file profilesFile
+		yaml.Unmarshal(configFile, &file)
+		profiles = map[string]sections{}
+		for name, profile := range file.Profiles {
+			profile := profile
+			profiles[name] = sections{&file.Defaults, &profile}
+		}
+	} else if err := decodeStrict(configFile, &gomtpConfig{}); err != nil {
+		addYAMLError(err)
+	}
+
+	for name, profile := range profiles {
+		// Type errors were reported by the strict decoding above
+		config := newConfig()
+		lines := map[string]int{}
+		for _, section := range []*yaml.Node{profile.defaults, profile.profile} {
+			if section.Kind != 0 {
+				section.Decode(&config)
+				recordKeyLines(section, "", lines)
+			}
+		}
+		envKeys, err := applyEnv(&config.EmailConfig)
+		if err != nil {
+			add(0, "environment", err.Error())
+		}
+		overridden := map[string]bool{}
+		for _, key := range envKeys {
+			overridden[key] = true
+		}
+
+		for _, problem := range smtpclient.ValidateConfig(&config.EmailConfig) {
+			message := problem.Error()
+			if name != "" {
+				message = fmt.Sprintf("profile %s: %s", name, message)
+			}
+			switch {
+			case overridden[problem.Key]:
+				add(0, envVariable(problem.Key), message)
+			case lines[problem.Key] > 0:
+				add(lines[problem.Key], "", message)
+			default:
+				add(0, path, message)
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if (a.line == 0) != (b.line == 0) {
+			return a.line != 0
+		}
+		if a.line != b.line {
+			return a.line < b.line
+		}
+		return a.String() < b.String()
+	})
+	return problems, nil
+}
+
+// Record the line of every key of a mapping, e.g. "port" or "oauth2.tokenUrl".
+func recordKeyLines(node *yaml.Node, prefix string, lines map[string]int) {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := prefix + node.Content[i].Value
+		lines[key] = node.Content[i].Line
+		recordKeyLines(node.Content[i+1], key+".", lines)
+	}
+}
+
+// Check the final configuration of a send or probe, after the flags are applied.
+// A probe never sends MAIL FROM, so it may leave from unset.
+func validateConfig(emailConfig *smtpclient.EmailConfig, sending bool) error {
+	var messages []string
+	for _, problem := range smtpclient.ValidateConfig(emailConfig) {
+		if !sending && problem.Key == "from" && emailConfig.From == "" {
+			continue
+		}
+		messages = append(messages, problem.Error())
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  %s", strings.Join(messages, "\n  "))
+}
@@ -0,0 +1,115 @@
+package smtpclient
+
+import (
+	"fmt"
+	"net/mail"
+)
+
+// AddressList is a list of RFC 5322 addresses, such as "Ops Alerts <ops@example.com>".
+// In YAML it can be written as a single string or as a list.
+type AddressList []string
+
+// UnmarshalYAML accepts a single address as well as a list.
+func (l *AddressList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var address string
+	if err := unmarshal(&address); err == nil {
+		*l = nil
+		if address != "" {
+			*l = AddressList{address}
+		}
+		return nil
+	}
+	var addresses []string
+	if err := unmarshal(&addresses); err != nil {
+		return err
+	}
+	*l = addresses
+	return nil
+}
+
+// parseAddress parses one address of the named field, e.g. "to".
+func parseAddress(field, address string) (*mail.Address, error) {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s address %q: %w", field, address, err)
+	}
+	return parsed, nil
+}
+
+// parseAddresses parses every address of the named field, skipping empty entries.
+func parseAddresses(field string, addresses []string) ([]*mail.Address, error) {
+	var parsed []*mail.Address
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+		a, err := parseAddress(field, address)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, a)
+	}
+	return parsed, nil
+}
+
+// NullReversePath is the envelopeFrom value that sends MAIL FROM:<>, as bounces do.
+const NullReversePath = "<>"
+
+// ValidateAddresses checks every address of the config.
+func ValidateAddresses(emailConfig *EmailConfig) error {
+	if emailConfig.From != "" || emailConfig.EnvelopeFrom != "" {
+		if _, err := envelopeSender(emailConfig); err != nil {
+			return err
+		}
+	}
+	if emailConfig.From != "" {
+		if _, err := parseAddress("from", emailConfig.From); err != nil {
+			return err
+		}
+	}
+	if emailConfig.Sender != "" {
+		if _, err := parseAddress("sender", emailConfig.Sender); err != nil {
+			return err
+		}
+	}
+	if _, err := parseAddresses("replyTo", emailConfig.ReplyTo); err != nil {
+		return err
+	}
+	_, err := envelopeRecipients(emailConfig)
+	return err
+}
+
+// envelopeSender returns the reverse-path for MAIL FROM: the envelopeFrom address when set,
+// "" for the null reverse-path, otherwise the From address.
+func envelopeSender(emailConfig *EmailConfig) (string, error) {
+	field, address := "envelopeFrom", emailConfig.EnvelopeFrom
+	switch address {
+	case NullReversePath:
+		return "", nil
+	case "":
+		field, address = "from", emailConfig.From
+	}
+	parsed, err := parseAddress(field, address)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Address, nil
+}
+
+// envelopeRecipients returns the bare To, Cc and Bcc addresses for RCPT TO.
+func envelopeRecipients(emailConfig *EmailConfig) ([]string, error) {
+	var recipients []string
+	for _, list := range []struct {
+		field     string
+		addresses []string
+	}{{"to", emailConfig.To}, {"cc", emailConfig.CcList}, {"bcc", emailConfig.BccList}} {
+		parsed, err := parseAddresses(list.field, list.addresses)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range parsed {
+			recipients = append(recipients, a.Address)
+		}
+	}
+	return recipients, nil
+}
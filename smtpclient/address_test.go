@@ -0,0 +1,122 @@
+package smtpclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAddressListYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		expected AddressList
+	}{
+		{"string", `to: 'Ops Alerts <ops@example.com>'`, AddressList{"Ops Alerts <ops@example.com>"}},
+		{"list", "to:\n  - 'a@example.com'\n  - 'B <b@example.com>'", AddressList{"a@example.com", "B <b@example.com>"}},
+		{"empty string", `to: ''`, nil},
+		{"missing", `from: 'from@example.com'`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var emailConfig EmailConfig
+			require.NoError(t, yaml.Unmarshal([]byte(tt.yaml), &emailConfig))
+			assert.Equal(t, tt.expected, emailConfig.To)
+		})
+	}
+
+	var emailConfig EmailConfig
+	assert.Error(t, yaml.Unmarshal([]byte("to:\n  name: 'a'"), &emailConfig))
+}
+
+func TestSendDisplayNames(t *testing.T) {
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	emailConfig.From = "Gomtp Bot <from@example.com>"
+	emailConfig.To = AddressList{"Ops Alerts <ops@example.com>", "dev@example.com"}
+	emailConfig.CcList = []string{"Jürgen Müller <juergen@example.com>"}
+	emailConfig.BccList = []string{"Audit <audit@example.com>"}
+
+	require.NoError(t, send(t, emailConfig))
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "from@example.com", messages[0].From)
+	assert.Equal(t, []string{"ops@example.com", "dev@example.com", "juergen@example.com", "audit@example.com"}, messages[0].Recipients)
+	assert.Contains(t, messages[0].Data, `From: "Gomtp Bot" <from@example.com>`)
+	assert.Contains(t, messages[0].Data, `To: "Ops Alerts" <ops@example.com>, dev@example.com`)
+	assert.Contains(t, messages[0].Data, `Cc: =?UTF-8?q?J=C3=BCrgen_M=C3=BCller?= <juergen@example.com>`)
+	assert.NotContains(t, messages[0].Data, "audit@example.com")
+}
+
+func TestSendEnvelopeFrom(t *testing.T) {
+	tests := []struct {
+		name         string
+		envelopeFrom string
+		mailFrom     string
+	}{
+		{"defaults to from", "", "from@example.com"},
+		{"bounce address", "Bounces <bounces+to=example.com@example.com>", "bounces+to=example.com@example.com"},
+		{"null reverse-path", NullReversePath, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, nil)
+			emailConfig := server.config()
+			emailConfig.From = "Gomtp Bot <from@example.com>"
+			emailConfig.EnvelopeFrom = tt.envelopeFrom
+			emailConfig.ReplyTo = AddressList{"Support <support@example.com>", "ops@example.com"}
+			emailConfig.Sender = "Relay <relay@example.com>"
+
+			client := NewClient(emailConfig)
+			ctx := context.Background()
+			require.NoError(t, client.Dial(ctx))
+			defer client.Close()
+			require.NoError(t, client.Send(ctx, newMessage(t, emailConfig)))
+
+			messages := server.received()
+			require.Len(t, messages, 1)
+			assert.Equal(t, tt.mailFrom, messages[0].From)
+			assert.Contains(t, messages[0].Data, `From: "Gomtp Bot" <from@example.com>`)
+			assert.Contains(t, messages[0].Data, `Reply-To: "Support" <support@example.com>, ops@example.com`)
+			assert.Contains(t, messages[0].Data, `Sender: "Relay" <relay@example.com>`)
+			assert.NotContains(t, messages[0].Data, "bounces")
+		})
+	}
+}
+
+func TestInvalidAddresses(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(emailConfig *EmailConfig)
+		err    string
+	}{
+		{"from", func(c *EmailConfig) { c.From = "not an address" }, `invalid from address "not an address": mail: no angle-addr`},
+		{"to", func(c *EmailConfig) { c.To = AddressList{"ok@example.com", "Ops <ops@>"} }, `invalid to address "Ops <ops@>"`},
+		{"cc", func(c *EmailConfig) { c.CcList = []string{"cc.example.com"} }, `invalid cc address "cc.example.com"`},
+		{"envelopeFrom", func(c *EmailConfig) { c.EnvelopeFrom = "bounces" }, `invalid envelopeFrom address "bounces"`},
+		{"replyTo", func(c *EmailConfig) { c.ReplyTo = AddressList{"reply@"} }, `invalid replyTo address "reply@"`},
+		{"sender", func(c *EmailConfig) { c.Sender = "Sender <sender.example.com>" }, `invalid sender address`},
+		{"bcc", func(c *EmailConfig) { c.BccList = []string{"bcc@example.com\r\nRCPT TO:<x@example.com>"} }, `invalid bcc address`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, nil)
+			emailConfig := server.config()
+			tt.config(emailConfig)
+
+			_, err := NewMessage(emailConfig)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+
+			client := NewClient(emailConfig)
+			err = client.Dial(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+			assert.Empty(t, client.Result().Phases, "invalid addresses are rejected before connecting")
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package smtpclient
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Attachment is a file to attach to the message. Path may be a glob pattern.
+type Attachment struct {
+	Path string `yaml:"path"`
+	// Name overrides the file name shown to the recipient; only valid when Path matches one file.
+	Name string `yaml:"name"`
+	// ContentType overrides the MIME type detected from the extension or content.
+	ContentType string `yaml:"type"`
+	// Inline embeds the file with a Content-ID equal to its name, e.g. for <img src="cid:logo.png">.
+	Inline bool `yaml:"inline"`
+}
+
+// UnmarshalYAML accepts a plain path as well as the full mapping.
+func (a *Attachment) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var path string
+	if err := unmarshal(&path); err == nil {
+		*a = Attachment{Path: path}
+		return nil
+	}
+	type attachment Attachment
+	return unmarshal((*attachment)(a))
+}
+
+// AttachmentFile is an attachment resolved to a single file on disk.
+type AttachmentFile struct {
+	Path        string
+	Name        string
+	ContentType string
+	Size        int64
+	Inline      bool
+}
+
+// ResolveAttachments expands globs, checks that every file exists and detects MIME types.
+func ResolveAttachments(attachments []Attachment) ([]AttachmentFile, error) {
+	var files []AttachmentFile
+	for _, a := range attachments {
+		paths := []string{a.Path}
+		if strings.ContainsAny(a.Path, "*?[") {
+			matches, err := filepath.Glob(a.Path)
+			if err != nil {
+				return nil, fmt.Errorf("attachment %q: %w", a.Path, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("attachment %q matches no files", a.Path)
+			}
+			if len(matches) > 1 && a.Name != "" {
+				return nil, fmt.Errorf("attachment %q: name can only be set when the path matches one file", a.Path)
+			}
+			paths = matches
+		}
+		for _, path := range paths {
+			file, err := resolveAttachment(a, path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func resolveAttachment(a Attachment, path string) (AttachmentFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return AttachmentFile{}, fmt.Errorf("attachment: %w", err)
+	}
+	if info.IsDir() {
+		return AttachmentFile{}, fmt.Errorf("attachment %q is a directory", path)
+	}
+
+	file := AttachmentFile{Path: path, Name: a.Name, ContentType: a.ContentType, Size: info.Size(), Inline: a.Inline}
+	if file.Name == "" {
+		file.Name = filepath.Base(path)
+	}
+	if file.ContentType == "" {
+		file.ContentType, err = detectContentType(path)
+		if err != nil {
+			return AttachmentFile{}, fmt.Errorf("attachment: %w", err)
+		}
+	} else if _, _, err := mime.ParseMediaType(file.ContentType); err != nil {
+		return AttachmentFile{}, fmt.Errorf("attachment %q: invalid type %q", path, file.ContentType)
+	}
+	return file, nil
+}
+
+// detectContentType guesses the MIME type from the extension, falling back to sniffing the content.
+func detectContentType(path string) (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return contentType, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// attach adds the file to m as a regular or inline attachment.
+func (file AttachmentFile) attach(m *gomail.Message) {
+	mediaType, params, _ := mime.ParseMediaType(file.ContentType)
+	params["name"] = file.Name
+	settings := []gomail.FileSetting{
+		gomail.Rename(file.Name),
+		gomail.SetHeader(map[string][]string{"Content-Type": {mime.FormatMediaType(mediaType, params)}}),
+	}
+	if file.Inline {
+		m.Embed(file.Path, settings...)
+	} else {
+		m.Attach(file.Path, settings...)
+	}
+}
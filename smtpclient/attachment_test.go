@@ -0,0 +1,111 @@
+package smtpclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestResolveAttachments(t *testing.T) {
+	dir := t.TempDir()
+	report := writeFile(t, dir, "report.txt", "all good")
+	writeFile(t, dir, "backup-1.log", "one")
+	writeFile(t, dir, "backup-2.log", "two")
+	blob := writeFile(t, dir, "blob", "%PDF-1.4")
+
+	files, err := ResolveAttachments([]Attachment{
+		{Path: report, Name: "summary.txt"},
+		{Path: filepath.Join(dir, "backup-*.log"), ContentType: "text/x-log"},
+		{Path: blob, Inline: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []AttachmentFile{
+		{Path: report, Name: "summary.txt", ContentType: "text/plain; charset=utf-8", Size: 8},
+		{Path: filepath.Join(dir, "backup-1.log"), Name: "backup-1.log", ContentType: "text/x-log", Size: 3},
+		{Path: filepath.Join(dir, "backup-2.log"), Name: "backup-2.log", ContentType: "text/x-log", Size: 3},
+		{Path: blob, Name: "blob", ContentType: "application/pdf", Size: 8, Inline: true},
+	}, files)
+}
+
+func TestResolveAttachmentsErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.log", "a")
+	writeFile(t, dir, "b.log", "b")
+
+	tests := []struct {
+		name       string
+		attachment Attachment
+		err        string
+	}{
+		{"missing file", Attachment{Path: filepath.Join(dir, "missing.txt")}, "no such file or directory"},
+		{"directory", Attachment{Path: dir}, "is a directory"},
+		{"glob without matches", Attachment{Path: filepath.Join(dir, "*.pdf")}, "matches no files"},
+		{"name for several files", Attachment{Path: filepath.Join(dir, "*.log"), Name: "logs.txt"}, "name can only be set"},
+		{"invalid type", Attachment{Path: filepath.Join(dir, "a.log"), ContentType: "not a type"}, "invalid type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ResolveAttachments([]Attachment{tt.attachment})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestAttachmentYAML(t *testing.T) {
+	var emailConfig EmailConfig
+	err := yaml.Unmarshal([]byte(`
+attachments:
+  - report.txt
+  - path: logo.png
+    name: brand.png
+    type: image/png
+    inline: true
+`), &emailConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []Attachment{
+		{Path: "report.txt"},
+		{Path: "logo.png", Name: "brand.png", ContentType: "image/png", Inline: true},
+	}, emailConfig.Attachments)
+}
+
+func TestSendAttachments(t *testing.T) {
+	dir := t.TempDir()
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	emailConfig.Body = "See attached."
+	emailConfig.Attachments = []Attachment{
+		{Path: writeFile(t, dir, "report.txt", "all good")},
+		{Path: writeFile(t, dir, "logo.png", "not really a png"), Inline: true},
+	}
+
+	require.NoError(t, send(t, emailConfig))
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	data := messages[0].Data
+	assert.Contains(t, data, "Content-Type: multipart/mixed;")
+	assert.Contains(t, data, "Content-Type: multipart/related;")
+	assert.Contains(t, data, `Content-Type: text/plain; charset=utf-8; name=report.txt`)
+	assert.Contains(t, data, `Content-Disposition: attachment; filename="report.txt"`)
+	assert.Contains(t, data, `Content-Type: image/png; name=logo.png`)
+	assert.Contains(t, data, `Content-Disposition: inline; filename="logo.png"`)
+	assert.Contains(t, data, "Content-ID: <logo.png>")
+}
+
+func TestNewMessageMissingAttachment(t *testing.T) {
+	emailConfig := &EmailConfig{Attachments: []Attachment{{Path: filepath.Join(t.TempDir(), "missing.txt")}}}
+	_, err := NewMessage(emailConfig)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
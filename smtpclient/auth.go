@@ -0,0 +1,211 @@
+package smtpclient
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+)
+
+// Values accepted by EmailConfig.Auth. An empty value behaves like AuthNone.
+const (
+	AuthNone        = "NO"
+	AuthPlain       = "PLAIN"
+	AuthLogin       = "LOGIN"
+	AuthCRAMMD5     = "CRAM-MD5"
+	AuthXOAuth2     = "XOAUTH2"
+	AuthOAuthBearer = "OAUTHBEARER"
+	AuthAuto        = "AUTO"
+)
+
+// Mechanisms AUTO may pick, strongest first. OAuth2 mechanisms are only
+// considered when an OAuth2 refresh token is configured.
+var (
+	autoPreference       = []string{AuthCRAMMD5, AuthPlain, AuthLogin}
+	autoOAuth2Preference = []string{AuthOAuthBearer, AuthXOAuth2}
+)
+
+// ErrNoAuthMechanism is returned when AUTO finds no usable mechanism among the advertised ones.
+var ErrNoAuthMechanism = errors.New("server advertises no supported AUTH mechanism")
+
+// authMechanism normalizes the configured auth value, returning "" when auth is disabled.
+func authMechanism(auth string) (string, error) {
+	mechanism := strings.ToUpper(strings.TrimSpace(auth))
+	switch mechanism {
+	case "", AuthNone:
+		return "", nil
+	case AuthPlain, AuthLogin, AuthCRAMMD5, AuthXOAuth2, AuthOAuthBearer, AuthAuto:
+		return mechanism, nil
+	default:
+		return "", fmt.Errorf("invalid configuration: %w", unsupportedAuth(auth))
+	}
+}
+
+func unsupportedAuth(auth string) error {
+	return fmt.Errorf("unsupported auth %q; choose one of NO | PLAIN | LOGIN | CRAM-MD5 | XOAUTH2 | OAUTHBEARER | AUTO", auth)
+}
+
+// usesOAuth2 reports whether the mechanism needs an access token from the OAuth2 token endpoint.
+func usesOAuth2(mechanism string, emailConfig *EmailConfig) bool {
+	switch mechanism {
+	case AuthXOAuth2, AuthOAuthBearer, AuthAuto:
+		return emailConfig.OAuth2.Enabled()
+	}
+	return false
+}
+
+// chooseAuthMechanism resolves AUTO against the mechanisms advertised in the EHLO reply.
+func chooseAuthMechanism(mechanism string, advertised []string, oauth2 bool) (string, error) {
+	if mechanism != AuthAuto {
+		return mechanism, nil
+	}
+	preference := autoPreference
+	if oauth2 {
+		preference = autoOAuth2Preference
+	}
+	for _, preferred := range preference {
+		for _, m := range advertised {
+			if strings.EqualFold(m, preferred) {
+				return preferred, nil
+			}
+		}
+	}
+	return "", ErrNoAuthMechanism
+}
+
+// newAuth creates the smtp.Auth implementing the given mechanism. secret is the
+// resolved password, or the bearer token for OAuth2 mechanisms.
+func newAuth(mechanism string, emailConfig *EmailConfig, secret string) smtp.Auth {
+	switch mechanism {
+	case AuthPlain:
+		return smtp.PlainAuth("", emailConfig.Username, secret, emailConfig.Host)
+	case AuthLogin:
+		return &loginAuth{username: emailConfig.Username, password: secret, host: emailConfig.Host}
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(emailConfig.Username, secret)
+	case AuthXOAuth2:
+		return &xoauth2Auth{username: emailConfig.Username, token: secret}
+	case AuthOAuthBearer:
+		return &oauthBearerAuth{username: emailConfig.Username, token: secret}
+	}
+	return nil
+}
+
+// auth runs the SASL exchange for a, mirroring smtp.Client.Auth, and returns the final reply.
+func (c *Client) auth(a smtp.Auth) (int, string, error) {
+	encoding := base64.StdEncoding
+	c.tracer.auth(true)
+	defer c.tracer.auth(false)
+	_, advertised := c.Extension("AUTH")
+	mech, resp, err := a.Start(&smtp.ServerInfo{Name: c.config.Host, TLS: c.tlsState != nil, Auth: strings.Fields(advertised)})
+	if err != nil {
+		return 0, "", err
+	}
+	code, msg64, err := c.cmd(0, "%s", strings.TrimSpace(fmt.Sprintf("AUTH %s %s", mech, encoding.EncodeToString(resp))))
+	for err == nil {
+		var msg []byte
+		switch code {
+		case 334:
+			msg, err = encoding.DecodeString(msg64)
+		case 235:
+			// the last message isn't base64 because it isn't a challenge
+			msg = []byte(msg64)
+		default:
+			err = &textproto.Error{Code: code, Msg: msg64}
+		}
+		if err == nil {
+			resp, err = a.Next(msg, code == 334)
+		}
+		if err != nil {
+			// abort the AUTH
+			if code == 334 {
+				c.cmd(501, "*")
+			}
+			break
+		}
+		if resp == nil {
+			break
+		}
+		code, msg64, err = c.cmd(0, "%s", encoding.EncodeToString(resp))
+	}
+	return code, msg64, err
+}
+
+// loginAuth implements the AUTH LOGIN mechanism.
+type loginAuth struct {
+	username, password, host string
+	step                     int
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Same safeguard as smtp.PlainAuth: never send credentials in the clear to a remote host.
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	a.step = 0
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	a.step++
+	switch a.step {
+	case 1:
+		return []byte(a.username), nil
+	case 2:
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+	}
+}
+
+// xoauth2Auth implements the XOAUTH2 mechanism with a bearer token.
+type xoauth2Auth struct {
+	username, token string
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		// The server sends error details as a challenge; an empty reply ends the exchange.
+		return []byte{}, nil
+	}
+	return nil, nil
+}
+
+// oauthBearerAuth implements the OAUTHBEARER mechanism (RFC 7628).
+type oauthBearerAuth struct {
+	username, token string
+}
+
+func (a *oauthBearerAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	return "OAUTHBEARER", []byte("n,a=" + a.username + ",\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+func (a *oauthBearerAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		// RFC 7628 section 3.2.3: acknowledge the error challenge with a single ^A.
+		return []byte("\x01"), nil
+	}
+	return nil, nil
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
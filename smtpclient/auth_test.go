@@ -0,0 +1,118 @@
+package smtpclient
+
+import (
+	"bytes"
+	"context"
+	"net/smtp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuthServer(t *testing.T, mechanisms ...string) *fakeServer {
+	return newFakeServer(t, func(s *fakeServer) {
+		s.authMechanisms = mechanisms
+		s.username = "user@example.com"
+		s.password = "secret"
+	})
+}
+
+func authConfig(server *fakeServer, auth string) *EmailConfig {
+	emailConfig := server.config()
+	emailConfig.Auth = auth
+	emailConfig.Username = "user@example.com"
+	emailConfig.Password = "secret"
+	return emailConfig
+}
+
+func TestAuthMechanisms(t *testing.T) {
+	for _, mechanism := range []string{AuthPlain, AuthLogin, AuthCRAMMD5, AuthXOAuth2} {
+		t.Run(mechanism, func(t *testing.T) {
+			server := newAuthServer(t, "PLAIN", "LOGIN", "CRAM-MD5", "XOAUTH2")
+			require.NoError(t, send(t, authConfig(server, mechanism)))
+			assert.Equal(t, mechanism, server.authMechanism())
+			assert.Len(t, server.received(), 1)
+		})
+	}
+}
+
+func TestAuthIsCaseInsensitive(t *testing.T) {
+	server := newAuthServer(t, "LOGIN")
+	require.NoError(t, send(t, authConfig(server, "login")))
+	assert.Equal(t, AuthLogin, server.authMechanism())
+}
+
+func TestAuthAutoPicksStrongest(t *testing.T) {
+	tests := map[string]struct {
+		advertised []string
+		expected   string
+	}{
+		"cram-md5 preferred": {[]string{"LOGIN", "PLAIN", "CRAM-MD5"}, AuthCRAMMD5},
+		"plain over login":   {[]string{"LOGIN", "PLAIN"}, AuthPlain},
+		"login only":         {[]string{"LOGIN"}, AuthLogin},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newAuthServer(t, tt.advertised...)
+			var debugOutput bytes.Buffer
+			client := NewClient(authConfig(server, AuthAuto))
+			client.Debug = &debugOutput
+
+			require.NoError(t, client.Dial(context.Background()))
+			client.Close()
+
+			assert.Equal(t, tt.expected, server.authMechanism())
+			assert.Contains(t, debugOutput.String(), "auth_mechanism="+tt.expected)
+		})
+	}
+}
+
+func TestAuthAutoWithoutUsableMechanism(t *testing.T) {
+	server := newAuthServer(t, "GSSAPI")
+	err := send(t, authConfig(server, AuthAuto))
+	assert.ErrorIs(t, err, ErrNoAuthMechanism)
+}
+
+func TestAuthInvalidCredentials(t *testing.T) {
+	server := newAuthServer(t, "PLAIN", "LOGIN", "CRAM-MD5", "XOAUTH2")
+	for _, mechanism := range []string{AuthPlain, AuthLogin, AuthCRAMMD5, AuthXOAuth2} {
+		emailConfig := authConfig(server, mechanism)
+		emailConfig.Password = "wrong"
+
+		err := send(t, emailConfig)
+		var smtpErr *Error
+		require.ErrorAs(t, err, &smtpErr, mechanism)
+		assert.Equal(t, PhaseAuth, smtpErr.Phase, mechanism)
+		assert.Equal(t, 535, smtpErr.Code, mechanism)
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	server := newAuthServer(t, "PLAIN")
+	require.NoError(t, send(t, authConfig(server, AuthNone)))
+	assert.Empty(t, server.authMechanism())
+}
+
+func TestAuthUnsupportedValue(t *testing.T) {
+	emailConfig := &EmailConfig{Host: "127.0.0.1", Port: 25, Auth: "NTLM"}
+	err := NewClient(emailConfig).Dial(context.Background())
+	assert.ErrorContains(t, err, `unsupported auth "NTLM"`)
+}
+
+func TestLoginAuthRefusesUnencryptedRemoteHost(t *testing.T) {
+	emailConfig := &EmailConfig{Host: "smtp.example.com", Username: "u"}
+	_, _, err := newAuth(AuthLogin, emailConfig, "p").Start(&smtp.ServerInfo{Name: "smtp.example.com"})
+	assert.ErrorContains(t, err, "unencrypted connection")
+}
+
+func TestOAuth2AuthRefusesUnencryptedRemoteHost(t *testing.T) {
+	emailConfig := &EmailConfig{Host: "smtp.example.com", Username: "u"}
+	for _, mechanism := range []string{AuthXOAuth2, AuthOAuthBearer} {
+		_, _, err := newAuth(mechanism, emailConfig, "token").Start(&smtp.ServerInfo{Name: "smtp.example.com"})
+		assert.ErrorContains(t, err, "unencrypted connection", mechanism)
+
+		_, _, err = newAuth(mechanism, emailConfig, "token").Start(&smtp.ServerInfo{Name: "smtp.example.com", TLS: true})
+		assert.NoError(t, err, mechanism)
+	}
+}
@@ -0,0 +1,537 @@
+package smtpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Client sends email through the SMTP server described by an EmailConfig.
+type Client struct {
+	config    *EmailConfig
+	tlsConfig *tls.Config
+	conn      net.Conn
+	text      *textproto.Conn
+
+	banner        string
+	extensions    []Extension
+	tlsState      *tls.ConnectionState
+	authMechanism string
+	result        Result
+	tracer        *tracer
+
+	// session is the result of Dial, which every Send starts from.
+	session Result
+
+	// secret is the resolved password, or the bearer token for OAuth2 mechanisms.
+	secret string
+
+	// Debug receives verbose SMTP/TLS details when set.
+	Debug io.Writer
+	// Trace receives the SMTP conversation, one timestamped line per command or reply, when set.
+	// AUTH payloads are always redacted.
+	Trace io.Writer
+	// TraceRedactBody replaces the message data in Trace with its size.
+	TraceRedactBody bool
+	// HTTPClient is used for OAuth2 token requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// Extension is a service extension advertised in the EHLO reply.
+type Extension struct {
+	Name   string
+	Params string
+}
+
+// NewClient creates a client for the given config. No connection is made until Dial.
+func NewClient(emailConfig *EmailConfig) *Client {
+	return &Client{config: emailConfig}
+}
+
+// Dial connects to the server, greets it, upgrades with STARTTLS and authenticates as configured.
+func (c *Client) Dial(ctx context.Context) (err error) {
+	emailConfig := c.config
+	c.result = Result{}
+	c.banner, c.extensions, c.tlsState, c.authMechanism = "", nil, nil, ""
+	defer func() { c.session = c.result }()
+	defer c.finish(time.Now(), &err)
+	c.tracer = nil
+	if c.Trace != nil {
+		c.tracer = &tracer{w: c.Trace, redactBody: c.TraceRedactBody}
+	}
+
+	// Validate mode selection
+	if emailConfig.SSL && emailConfig.TLS {
+		return ErrSSLAndTLS
+	}
+	if err := ValidateAddresses(emailConfig); err != nil {
+		return err
+	}
+	mechanism, err := authMechanism(emailConfig.Auth)
+	if err != nil {
+		return err
+	}
+	c.tlsConfig, err = newTLSConfig(emailConfig)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the password and exchange the refresh token before connecting so a bad secret never opens a session.
+	// Without AUTH the password is left alone, so its command is not run.
+	c.secret = ""
+	if mechanism != "" {
+		c.secret, err = resolvePassword(ctx, emailConfig)
+		if err != nil {
+			return err
+		}
+	}
+	if usesOAuth2(mechanism, emailConfig) {
+		httpClient := c.HTTPClient
+		if httpClient == nil {
+			httpClient = http.DefaultClient
+		}
+		c.secret, err = fetchAccessToken(ctx, httpClient, emailConfig.OAuth2)
+		if err != nil {
+			return newError(PhaseAuth, err)
+		}
+		c.debugf("oauth2_token_refreshed token_url=%s\n", emailConfig.OAuth2.TokenURL)
+	}
+
+	c.debugf("host=%s port=%d ssl=%t starttls=%t auth=%s verifyCert=%t\n", emailConfig.Host, emailConfig.Port, emailConfig.SSL, emailConfig.TLS, emailConfig.Auth, emailConfig.VerifiesCertificate())
+	switch {
+	case emailConfig.SSL:
+		c.debugf("selected_mode=ssl_implicit\n")
+	case emailConfig.TLS:
+		c.debugf("selected_mode=starttls\n")
+	default:
+		c.debugf("selected_mode=plain_no_tls\n")
+	}
+
+	// Connect
+	start := time.Now()
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return c.record(PhaseConnect, start, 0, "", err)
+	}
+	c.setConn(conn)
+
+	stop := c.watch(ctx)
+	defer stop()
+
+	err = c.handshake(ctx, mechanism, start)
+	if err != nil {
+		c.reset()
+		return err
+	}
+	return nil
+}
+
+// connect resolves the host and dials its addresses in turn, completing the
+// TLS handshake right away in implicit TLS mode. Each step is timed separately.
+func (c *Client) connect(ctx context.Context) (net.Conn, error) {
+	emailConfig := c.config
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, emailConfig.Host)
+	c.timing(StepDNS, emailConfig.Host, start)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	var conn net.Conn
+	for _, ip := range addrs {
+		addr := net.JoinHostPort(ip, strconv.Itoa(emailConfig.Port))
+		start := time.Now()
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+		c.timing(StepTCP, addr, start)
+		if err == nil {
+			c.tracer.event("connected to %s", addr)
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if emailConfig.SSL {
+		start := time.Now()
+		tlsConn := tls.Client(conn, c.tlsConfig)
+		err := tlsConn.HandshakeContext(ctx)
+		c.timing(StepTLS, c.tlsConfig.ServerName, start)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+	return conn, nil
+}
+
+func (c *Client) handshake(ctx context.Context, mechanism string, start time.Time) error {
+	emailConfig := c.config
+
+	// Greeting
+	greetingStart := time.Now()
+	code, banner, err := c.text.ReadResponse(220)
+	c.timing(StepGreeting, "", greetingStart)
+	if err := c.record(PhaseConnect, start, code, banner, err); err != nil {
+		return err
+	}
+	c.banner = banner
+
+	// EHLO/HELO
+	if err := c.hello(); err != nil {
+		return err
+	}
+
+	// STARTTLS if requested
+	if emailConfig.TLS {
+		start := time.Now()
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return c.record(PhaseStartTLS, start, 0, "", ErrStartTLSNotSupported)
+		}
+		code, msg, err := c.cmd(220, "STARTTLS")
+		c.timing(StepStartTLS, "", start)
+		if err == nil {
+			handshakeStart := time.Now()
+			tlsConn := tls.Client(c.conn, c.tlsConfig)
+			err = tlsConn.HandshakeContext(ctx)
+			c.timing(StepTLS, c.tlsConfig.ServerName, handshakeStart)
+			if err == nil {
+				c.setConn(tlsConn)
+			}
+		}
+		if err := c.record(PhaseStartTLS, start, code, msg, err); err != nil {
+			return err
+		}
+
+		// The extensions advertised before STARTTLS must be discarded
+		if err := c.hello(); err != nil {
+			return err
+		}
+	}
+
+	// AUTH if configured and supported
+	if mechanism != "" {
+		if ok, advertised := c.Extension("AUTH"); ok {
+			start := time.Now()
+			mechanism, err := chooseAuthMechanism(mechanism, strings.Fields(advertised), usesOAuth2(mechanism, emailConfig))
+			if err != nil {
+				return c.record(PhaseAuth, start, 0, "", err)
+			}
+			c.debugf("auth_advertised=%q auth_mechanism=%s\n", advertised, mechanism)
+			code, msg, err := c.auth(newAuth(mechanism, emailConfig, c.secret))
+			c.timing(StepAuth, mechanism, start)
+			if err := c.record(PhaseAuth, start, code, msg, err); err != nil {
+				return err
+			}
+			c.authMechanism = mechanism
+		} else {
+			c.debugf("server does not advertise AUTH; skipping auth\n")
+		}
+	}
+	return nil
+}
+
+// Send transfers the message to the To, Cc and Bcc recipients of the config.
+// Every recipient is tried before a rejection is reported.
+func (c *Client) Send(ctx context.Context, m *gomail.Message) (err error) {
+	if c.text == nil {
+		return ErrNotConnected
+	}
+	emailConfig := c.config
+	// Drop the phases and recipients of the previous message. The slices are clipped so
+	// appending does not overwrite a Result returned after the previous Send.
+	c.result = c.session
+	c.result.Phases, c.result.Timings = slices.Clip(c.result.Phases), slices.Clip(c.result.Timings)
+	defer c.finish(time.Now(), &err)
+
+	// Render message to bytes once
+	var msgBuf bytes.Buffer
+	if _, err := m.WriteTo(&msgBuf); err != nil {
+		return err
+	}
+
+	stop := c.watch(ctx)
+	defer stop()
+
+	reversePath, err := envelopeSender(emailConfig)
+	if err != nil {
+		return err
+	}
+	recipients, err := envelopeRecipients(emailConfig)
+	if err != nil {
+		return err
+	}
+
+	// MAIL FROM
+	start := time.Now()
+	code, msg, err := c.mail(reversePath)
+	if reversePath == "" {
+		c.timing(StepMail, NullReversePath, start)
+	} else {
+		c.timing(StepMail, reversePath, start)
+	}
+	if err := c.record(PhaseMail, start, code, msg, err); err != nil {
+		return err
+	}
+
+	// RCPT TO (To + Cc + Bcc)
+	var rcptErr error
+	for _, rcpt := range recipients {
+		start := time.Now()
+		code, msg, err := 0, "", validateLine(rcpt)
+		if err == nil {
+			code, msg, err = c.cmd(25, "RCPT TO:<%s>", rcpt)
+		}
+		c.timing(StepRcpt, rcpt, start)
+		c.result.Recipients = append(c.result.Recipients, RecipientResult{Address: rcpt, Accepted: err == nil, Reply: replyOf(code, msg, err)})
+		if err := c.record(PhaseRcpt, start, code, msg, err); err != nil && rcptErr == nil {
+			rcptErr = err
+		}
+	}
+	if rcptErr != nil {
+		// Abort the transaction so the connection can send another message
+		c.cmd(250, "RSET")
+		return rcptErr
+	}
+
+	// DATA
+	start = time.Now()
+	code, msg, err = c.data(msgBuf.Bytes())
+	c.timing(StepData, "", start)
+	if err := c.record(PhaseData, start, code, msg, err); err != nil {
+		return err
+	}
+	c.result.QueueID = ParseQueueID(msg)
+
+	c.result.Success = true
+	return nil
+}
+
+// data sends the message content and returns the final reply.
+func (c *Client) data(msg []byte) (int, string, error) {
+	if code, msg, err := c.cmd(354, "DATA"); err != nil {
+		return code, msg, err
+	}
+	c.tracer.body(true)
+	wc := c.text.DotWriter()
+	_, err := wc.Write(msg)
+	if closeErr := wc.Close(); err == nil {
+		err = closeErr
+	}
+	c.tracer.body(false)
+	if err != nil {
+		return 0, "", err
+	}
+	return c.text.ReadResponse(250)
+}
+
+// Close ends the session with QUIT and closes the connection.
+func (c *Client) Close() error {
+	if c.text == nil {
+		c.reset()
+		return nil
+	}
+	_, _, err := c.cmd(221, "QUIT")
+	c.reset()
+	return err
+}
+
+// Banner returns the greeting the server sent after connecting.
+func (c *Client) Banner() string {
+	return c.banner
+}
+
+// Extensions returns the service extensions from the latest EHLO reply, in advertised order.
+func (c *Client) Extensions() []Extension {
+	return c.extensions
+}
+
+// Extension reports whether the server advertised the named extension, and its parameters.
+func (c *Client) Extension(name string) (bool, string) {
+	for _, ext := range c.extensions {
+		if strings.EqualFold(ext.Name, name) {
+			return true, ext.Params
+		}
+	}
+	return false, ""
+}
+
+// TLSConnectionState returns the state of the implicit TLS or STARTTLS connection, if any.
+func (c *Client) TLSConnectionState() (tls.ConnectionState, bool) {
+	if c.tlsState == nil {
+		return tls.ConnectionState{}, false
+	}
+	return *c.tlsState, true
+}
+
+// Result returns what happened during the latest Dial and Send.
+func (c *Client) Result() *Result {
+	result := c.result
+	if c.tlsState != nil {
+		result.TLS = newTLSInfo(*c.tlsState)
+	}
+	return &result
+}
+
+// AuthMechanism returns the mechanism used to authenticate, or "" when no AUTH took place.
+func (c *Client) AuthMechanism() string {
+	return c.authMechanism
+}
+
+// setConn switches the session to conn, e.g. after the TLS handshake.
+// The trace sees the plaintext, so a TLS conn is wrapped rather than the TCP conn beneath it.
+func (c *Client) setConn(conn net.Conn) {
+	c.conn = conn
+	c.text = textproto.NewConn(c.tracer.wrap(conn))
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		c.tlsState = &state
+		c.debugTLSState(state)
+		c.tracer.event("TLS established: %s %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	}
+}
+
+// reset drops the connection without saying goodbye to the server.
+func (c *Client) reset() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = nil
+	c.text = nil
+}
+
+// cmd sends a command and reads the reply, which must match expectCode.
+func (c *Client) cmd(expectCode int, format string, args ...any) (int, string, error) {
+	id, err := c.text.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	c.text.StartResponse(id)
+	defer c.text.EndResponse(id)
+	return c.text.ReadResponse(expectCode)
+}
+
+// record adds the outcome of a phase to the result, returning err wrapped as an *Error.
+func (c *Client) record(phase Phase, start time.Time, code int, msg string, err error) error {
+	p := PhaseResult{Phase: phase, Reply: replyOf(code, msg, err), Duration: time.Since(start)}
+	c.result.Phase = phase
+	if err != nil {
+		p.Error = err.Error()
+		c.result.Phases = append(c.result.Phases, p)
+		return newError(phase, err)
+	}
+	c.result.Phases = append(c.result.Phases, p)
+	return nil
+}
+
+// timing adds the latency of one step, measured from start, to the result.
+func (c *Client) timing(step, target string, start time.Time) {
+	c.result.Timings = append(c.result.Timings, Timing{Step: step, Target: target, Duration: time.Since(start)})
+}
+
+// finish accounts the time spent in Dial or Send and notes the error, if any.
+func (c *Client) finish(start time.Time, err *error) {
+	c.result.Duration += time.Since(start)
+	c.result.Error = ""
+	c.result.CertificateDiagnosis = nil
+	if *err != nil {
+		c.result.Error = (*err).Error()
+		var certErr *CertificateError
+		if errors.As(*err, &certErr) {
+			c.result.CertificateDiagnosis = certErr.Diagnosis
+		}
+	}
+}
+
+// hello sends EHLO, falling back to HELO, and records the advertised extensions.
+func (c *Client) hello() error {
+	c.extensions = nil
+	start := time.Now()
+	code, msg, err := c.cmd(250, "EHLO %s", c.config.Host)
+	defer c.timing(StepEhlo, c.config.Host, start)
+	if err != nil {
+		code, msg, heloErr := c.cmd(250, "HELO %s", c.config.Host)
+		if heloErr != nil {
+			return c.record(PhaseEhlo, start, 0, "", err)
+		}
+		return c.record(PhaseEhlo, start, code, msg, nil)
+	}
+	if err := c.record(PhaseEhlo, start, code, strings.SplitN(msg, "\n", 2)[0], nil); err != nil {
+		return err
+	}
+	lines := strings.Split(msg, "\n")
+	for _, line := range lines[1:] {
+		name, params, _ := strings.Cut(line, " ")
+		c.extensions = append(c.extensions, Extension{Name: strings.ToUpper(name), Params: params})
+	}
+	return nil
+}
+
+// mail issues MAIL FROM with the parameters the server supports.
+func (c *Client) mail(from string) (int, string, error) {
+	if err := validateLine(from); err != nil {
+		return 0, "", err
+	}
+	cmdStr := "MAIL FROM:<%s>"
+	if ok, _ := c.Extension("8BITMIME"); ok {
+		cmdStr += " BODY=8BITMIME"
+	}
+	if ok, _ := c.Extension("SMTPUTF8"); ok {
+		cmdStr += " SMTPUTF8"
+	}
+	return c.cmd(250, cmdStr, from)
+}
+
+// validateLine rejects values that would smuggle extra SMTP commands.
+func validateLine(line string) error {
+	if strings.ContainsAny(line, "\r\n") {
+		return fmt.Errorf("a line must not contain CR or LF")
+	}
+	return nil
+}
+
+// watch aborts pending network I/O once ctx is done. The returned func stops watching.
+func (c *Client) watch(ctx context.Context) func() {
+	conn := c.conn
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	return func() {
+		if stop() {
+			conn.SetDeadline(time.Time{})
+		}
+	}
+}
+
+func (c *Client) debugf(format string, args ...any) {
+	if c.Debug != nil {
+		fmt.Fprintf(c.Debug, "[gomtp][debug] "+format, args...)
+	}
+}
+
+func (c *Client) debugTLSState(st tls.ConnectionState) {
+	c.debugf("negotiated_tls=version:%q cipher_suite:%q server_name=%s\n", tls.VersionName(st.Version), tls.CipherSuiteName(st.CipherSuite), st.ServerName)
+	if len(st.PeerCertificates) > 0 {
+		cert := st.PeerCertificates[0]
+		c.debugf("cert_subject=%s issuer=%s not_before=%s not_after=%s dns_names=%v pin_sha256=%s\n",
+			cert.Subject.String(), cert.Issuer.String(), cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339), cert.DNSNames, SPKIPin(cert))
+	}
+}
@@ -0,0 +1,246 @@
+package smtpclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/gomail.v2"
+)
+
+func send(t *testing.T, emailConfig *EmailConfig) error {
+	t.Helper()
+	client := NewClient(emailConfig)
+	ctx := context.Background()
+	if err := client.Dial(ctx); err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Send(ctx, newMessage(t, emailConfig))
+}
+
+func newMessage(t *testing.T, emailConfig *EmailConfig) *gomail.Message {
+	t.Helper()
+	m, err := NewMessage(emailConfig)
+	require.NoError(t, err)
+	return m
+}
+
+func TestSendPlain(t *testing.T) {
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	emailConfig.Subject = "Plain Subject"
+	emailConfig.Body = "Plain body"
+	emailConfig.CcList = []string{"cc1@example.com", "cc2@example.com"}
+
+	require.NoError(t, send(t, emailConfig))
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "from@example.com", messages[0].From)
+	assert.Equal(t, []string{"to@example.com", "cc1@example.com", "cc2@example.com"}, messages[0].Recipients)
+	assert.Contains(t, messages[0].Data, "Subject: Plain Subject")
+	assert.Contains(t, messages[0].Data, "Plain body")
+}
+
+func TestSendBcc(t *testing.T) {
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	emailConfig.CcList = []string{"cc@example.com"}
+	emailConfig.BccList = []string{"bcc1@example.com", "bcc2@example.com"}
+
+	require.NoError(t, send(t, emailConfig))
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	assert.Equal(t, []string{"to@example.com", "cc@example.com", "bcc1@example.com", "bcc2@example.com"}, messages[0].Recipients)
+	assert.NotContains(t, messages[0].Data, "Bcc")
+	assert.NotContains(t, messages[0].Data, "bcc1@example.com")
+	assert.NotContains(t, messages[0].Data, "bcc2@example.com")
+}
+
+func TestSendStartTLS(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = newTestTLSConfig(t)
+	})
+	emailConfig := server.config()
+	emailConfig.TLS = true
+
+	require.NoError(t, send(t, emailConfig))
+	assert.Len(t, server.received(), 1)
+}
+
+func TestSendImplicitTLS(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = newTestTLSConfig(t)
+		s.implicitTLS = true
+	})
+	emailConfig := server.config()
+
+	require.NoError(t, send(t, emailConfig))
+	assert.Len(t, server.received(), 1)
+}
+
+func TestUntrustedCertificate(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = newTestTLSConfig(t)
+	})
+	emailConfig := server.config()
+	emailConfig.TLS = true
+	emailConfig.VerifyCertificate = nil
+
+	err := send(t, emailConfig)
+	var smtpErr *Error
+	require.ErrorAs(t, err, &smtpErr)
+	assert.Equal(t, PhaseStartTLS, smtpErr.Phase)
+	assert.Contains(t, err.Error(), "certificate")
+}
+
+func TestStartTLSNotSupported(t *testing.T) {
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	emailConfig.TLS = true
+
+	err := send(t, emailConfig)
+	assert.ErrorIs(t, err, ErrStartTLSNotSupported)
+}
+
+func TestSSLAndTLS(t *testing.T) {
+	emailConfig := &EmailConfig{Host: "127.0.0.1", Port: 25, SSL: true, TLS: true}
+	err := NewClient(emailConfig).Dial(context.Background())
+	assert.ErrorIs(t, err, ErrSSLAndTLS)
+}
+
+func TestRejectedRecipient(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.rejectRcpt["rejected@example.com"] = true
+	})
+	emailConfig := server.config()
+	emailConfig.CcList = []string{"rejected@example.com"}
+
+	err := send(t, emailConfig)
+	var smtpErr *Error
+	require.True(t, errors.As(err, &smtpErr))
+	assert.Equal(t, PhaseRcpt, smtpErr.Phase)
+	assert.Equal(t, 550, smtpErr.Code)
+	assert.Empty(t, server.received())
+}
+
+func TestSendWithoutDial(t *testing.T) {
+	emailConfig := &EmailConfig{From: "from@example.com", To: AddressList{"to@example.com"}}
+	err := NewClient(emailConfig).Send(context.Background(), newMessage(t, emailConfig))
+	assert.ErrorIs(t, err, ErrNotConnected)
+}
+
+func TestDialCanceled(t *testing.T) {
+	server := newFakeServer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewClient(server.config()).Dial(ctx)
+	var smtpErr *Error
+	require.ErrorAs(t, err, &smtpErr)
+	assert.Equal(t, PhaseConnect, smtpErr.Phase)
+}
+
+func TestSessionDetails(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = newTestTLSConfig(t)
+		s.extensions = []string{"SIZE 10240000", "PIPELINING", "8BITMIME"}
+		s.authMechanisms = []string{"PLAIN", "LOGIN"}
+		s.username = "user@example.com"
+		s.password = "secret"
+	})
+	emailConfig := authConfig(server, AuthAuto)
+	emailConfig.TLS = true
+
+	client := NewClient(emailConfig)
+	require.NoError(t, client.Dial(context.Background()))
+	defer client.Close()
+
+	assert.Equal(t, "fake.example.com ESMTP ready", client.Banner())
+	assert.Equal(t, []Extension{
+		{Name: "SIZE", Params: "10240000"},
+		{Name: "PIPELINING"},
+		{Name: "8BITMIME"},
+		{Name: "AUTH", Params: "PLAIN LOGIN"},
+	}, client.Extensions(), "extensions advertised before STARTTLS must be replaced")
+
+	ok, size := client.Extension("size")
+	assert.True(t, ok)
+	assert.Equal(t, "10240000", size)
+
+	state, ok := client.TLSConnectionState()
+	require.True(t, ok)
+	assert.Len(t, state.PeerCertificates, 1)
+	assert.Equal(t, AuthPlain, client.AuthMechanism())
+}
+
+func TestSessionDetailsWithoutTLS(t *testing.T) {
+	server := newFakeServer(t, nil)
+	client := NewClient(server.config())
+	require.NoError(t, client.Dial(context.Background()))
+	defer client.Close()
+
+	_, ok := client.TLSConnectionState()
+	assert.False(t, ok)
+	assert.Empty(t, client.AuthMechanism())
+}
+
+func TestSendBodies(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		bodyHTML     string
+		textFromHTML bool
+		contains     []string
+		notContains  []string
+	}{
+		{
+			name:        "text only",
+			body:        "Plain body",
+			contains:    []string{"Content-Type: text/plain; charset=UTF-8", "Plain body"},
+			notContains: []string{"multipart/alternative", "text/html"},
+		},
+		{
+			name:        "html only",
+			bodyHTML:    "<p>HTML body</p>",
+			contains:    []string{"Content-Type: text/html; charset=UTF-8", "<p>HTML body</p>"},
+			notContains: []string{"multipart/alternative", "text/plain"},
+		},
+		{
+			name:     "text and html",
+			body:     "Plain body",
+			bodyHTML: "<p>HTML body</p>",
+			contains: []string{"Content-Type: multipart/alternative;", "Content-Type: text/plain; charset=UTF-8", "Plain body", "Content-Type: text/html; charset=UTF-8", "<p>HTML body</p>"},
+		},
+		{
+			name:         "text generated from html",
+			bodyHTML:     "<p>HTML <b>body</b></p>",
+			textFromHTML: true,
+			contains:     []string{"Content-Type: multipart/alternative;", "Content-Type: text/plain; charset=UTF-8", "\n\nHTML body\n", "<p>HTML <b>body</b></p>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, nil)
+			emailConfig := server.config()
+			emailConfig.Body = tt.body
+			emailConfig.BodyHTML = tt.bodyHTML
+			emailConfig.TextFromHTML = tt.textFromHTML
+
+			require.NoError(t, send(t, emailConfig))
+
+			messages := server.received()
+			require.Len(t, messages, 1)
+			for _, s := range tt.contains {
+				assert.Contains(t, messages[0].Data, s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, messages[0].Data, s)
+			}
+		})
+	}
+}
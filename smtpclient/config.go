@@ -0,0 +1,44 @@
+package smtpclient
+
+// EmailConfig holds everything needed to connect to an SMTP server and send a message.
+// The zero value verifies the server certificate; set VerifyCertificate to a pointer to false to skip it.
+type EmailConfig struct {
+	Username          string            `yaml:"username"`
+	Password          string            `yaml:"password"`
+	PasswordCommand   string            `yaml:"passwordCommand"`
+	From              string            `yaml:"from"`
+	EnvelopeFrom      string            `yaml:"envelopeFrom"`
+	ReplyTo           AddressList       `yaml:"replyTo"`
+	Sender            string            `yaml:"sender"`
+	To                AddressList       `yaml:"to"`
+	Host              string            `yaml:"host"`
+	Port              int               `yaml:"port"`
+	SSL               bool              `yaml:"ssl"`
+	TLS               bool              `yaml:"tls"`
+	Auth              string            `yaml:"auth"`
+	VerifyCertificate *bool             `yaml:"verifyCertificate"`
+	CAFile            string            `yaml:"caFile"`
+	CADir             string            `yaml:"caDir"`
+	ClientCert        string            `yaml:"clientCert"`
+	ClientKey         string            `yaml:"clientKey"`
+	TLSMinVersion     string            `yaml:"tlsMinVersion"`
+	TLSMaxVersion     string            `yaml:"tlsMaxVersion"`
+	CipherSuites      []string          `yaml:"cipherSuites"`
+	CurvePreferences  []string          `yaml:"curvePreferences"`
+	PinSHA256         []string          `yaml:"pinSha256"`
+	Subject           string            `yaml:"subject"`
+	Body              string            `yaml:"body"`
+	BodyHTML          string            `yaml:"bodyHtml"`
+	TextFromHTML      bool              `yaml:"textFromHtml"`
+	CcList            []string          `yaml:"cc"`
+	BccList           []string          `yaml:"bcc"`
+	OAuth2            OAuth2Config      `yaml:"oauth2"`
+	Attachments       []Attachment      `yaml:"attachments"`
+	Headers           map[string]string `yaml:"headers"`
+	OverrideHeaders   bool              `yaml:"overrideHeaders"`
+}
+
+// VerifiesCertificate reports whether the server certificate is verified, which it is unless VerifyCertificate is false.
+func (c *EmailConfig) VerifiesCertificate() bool {
+	return c.VerifyCertificate == nil || *c.VerifyCertificate
+}
@@ -0,0 +1,161 @@
+package smtpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// CertificateError is returned when the certificate chain of the server fails verification,
+// for implicit TLS as well as STARTTLS. Error returns the x509 error; Diagnosis explains it.
+type CertificateError struct {
+	Diagnosis *ChainDiagnosis
+	Err       error
+}
+
+func (e *CertificateError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+// ChainDiagnosis describes the chain presented by the server and what is wrong with it.
+type ChainDiagnosis struct {
+	Host  string            `json:"host"`
+	Chain []CertificateInfo `json:"chain"`
+	// OCSP is the status of the stapled OCSP response, e.g. "good" or "not stapled".
+	OCSP     string         `json:"ocsp"`
+	Problems []ChainProblem `json:"problems"`
+}
+
+// ChainProblem is one reason the chain failed verification, with the config change that addresses it.
+type ChainProblem struct {
+	Problem    string `json:"problem"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// verifyChain verifies the chain like crypto/tls does. It runs in VerifyConnection rather than
+// in crypto/tls itself so that the OCSP staple is still at hand to diagnose a failure.
+func verifyChain(state tls.ConnectionState, host string, roots *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("tls: server sent no certificates")
+	}
+	opts := x509.VerifyOptions{DNSName: host, Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := state.PeerCertificates[0].Verify(opts); err != nil {
+		return &CertificateError{
+			Diagnosis: diagnoseChain(state, host, err, time.Now()),
+			Err:       &tls.CertificateVerificationError{UnverifiedCertificates: state.PeerCertificates, Err: err},
+		}
+	}
+	return nil
+}
+
+// diagnoseChain checks the chain for every problem it has, not only the one x509 reported first.
+func diagnoseChain(state tls.ConnectionState, host string, verifyErr error, now time.Time) *ChainDiagnosis {
+	chain := state.PeerCertificates
+	leaf := chain[0]
+	diagnosis := &ChainDiagnosis{Host: host, Chain: newTLSInfo(state).Certificates, Problems: []ChainProblem{}}
+	add := func(suggestion, format string, args ...interface{}) {
+		diagnosis.Problems = append(diagnosis.Problems, ChainProblem{Problem: fmt.Sprintf(format, args...), Suggestion: suggestion})
+	}
+
+	for _, cert := range chain {
+		if now.After(cert.NotAfter) {
+			add("renew the certificate on the server, or set verifyCertificate: false while testing",
+				"%s expired on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+		} else if now.Before(cert.NotBefore) {
+			add("check the clock of this machine and of the server",
+				"%s is not valid before %s", cert.Subject, cert.NotBefore.Format(time.RFC3339))
+		}
+	}
+
+	if err := leaf.VerifyHostname(host); err != nil {
+		add("set host to a name the certificate is valid for",
+			"the server certificate is valid for %s, not %s", certificateNames(leaf), host)
+	}
+
+	for i := 0; i+1 < len(chain); i++ {
+		if chain[i].CheckSignatureFrom(chain[i+1]) != nil {
+			add("configure the server to send its chain in order, starting with its own certificate",
+				"%s was not issued by the next certificate of the chain, %s", chain[i].Subject, chain[i+1].Subject)
+		}
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(verifyErr, &unknownAuthority) {
+		last := chain[len(chain)-1]
+		switch {
+		case len(chain) == 1 && isSelfSigned(leaf):
+			add(fmt.Sprintf("add the certificate to caFile, or pin it with pinSha256: %s", SPKIPin(leaf)),
+				"the server certificate is self-signed")
+		case isSelfSigned(last):
+			add("add the root to caFile or caDir if it is a private CA",
+				"the chain ends at the self-signed root %s, which is not trusted", last.Subject)
+		default:
+			add(fmt.Sprintf("configure the server to send its intermediate certificates, or add %s to caFile if it is a private CA", last.Issuer),
+				"the chain ends at %s, whose issuer %s was not sent by the server and is not a trusted root", last.Subject, last.Issuer)
+		}
+	}
+
+	diagnosis.OCSP = ocspStatus(state, add)
+
+	if len(diagnosis.Problems) == 0 {
+		add("set verifyCertificate: false to skip verification while testing", "%s", verifyErr)
+	}
+	return diagnosis
+}
+
+// isSelfSigned checks the signature directly, as CheckSignatureFrom rejects a parent that is not a CA.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return cert.Subject.String() == cert.Issuer.String() &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// certificateNames lists the DNS names and IP addresses of the certificate, or its common name without them.
+func certificateNames(cert *x509.Certificate) string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 {
+		if cert.Subject.CommonName == "" {
+			return "no names"
+		}
+		return cert.Subject.CommonName + " (common name only, which is not checked)"
+	}
+	return strings.Join(names, ", ")
+}
+
+// ocspStatus describes the stapled OCSP response, reporting a revoked certificate as a problem.
+func ocspStatus(state tls.ConnectionState, add func(suggestion, format string, args ...interface{})) string {
+	if len(state.OCSPResponse) == 0 {
+		return "not stapled"
+	}
+	var issuer *x509.Certificate
+	if len(state.PeerCertificates) > 1 {
+		issuer = state.PeerCertificates[1]
+	}
+	response, err := ocsp.ParseResponseForCert(state.OCSPResponse, state.PeerCertificates[0], issuer)
+	if err != nil {
+		return "invalid: " + err.Error()
+	}
+	switch response.Status {
+	case ocsp.Good:
+		return "good, next update " + response.NextUpdate.Format(time.RFC3339)
+	case ocsp.Revoked:
+		add("replace the certificate on the server",
+			"the server certificate was revoked on %s", response.RevokedAt.Format(time.RFC3339))
+		return "revoked"
+	}
+	return "unknown"
+}
@@ -0,0 +1,201 @@
+package smtpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+// intermediate creates a CA signed by ca.
+func (ca *testCA) intermediate(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// staple adds an OCSP response signed by ca to the server certificate.
+func (ca *testCA) staple(t *testing.T, cert *tls.Certificate, status int) {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	template := ocsp.Response{
+		Status:       status,
+		SerialNumber: leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   time.Now().Add(24 * time.Hour),
+		RevokedAt:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	cert.OCSPStaple, err = ocsp.CreateResponse(ca.cert, ca.cert, template, ca.key)
+	require.NoError(t, err)
+}
+
+// diagnose dials the server and returns the certificate error it fails with.
+func diagnose(t *testing.T, emailConfig *EmailConfig) *CertificateError {
+	t.Helper()
+	emailConfig.VerifyCertificate = nil
+	client, err := dial(t, emailConfig)
+	var certErr *CertificateError
+	require.ErrorAs(t, err, &certErr)
+	assert.Same(t, certErr.Diagnosis, client.Result().CertificateDiagnosis)
+	return certErr
+}
+
+func problems(diagnosis *ChainDiagnosis) []string {
+	var list []string
+	for _, p := range diagnosis.Problems {
+		list = append(list, p.Problem)
+	}
+	return list
+}
+
+func TestDiagnoseSelfSigned(t *testing.T) {
+	for _, implicitTLS := range []bool{false, true} {
+		name := map[bool]string{false: "starttls", true: "implicit"}[implicitTLS]
+		t.Run(name, func(t *testing.T) {
+			server := newFakeServer(t, func(s *fakeServer) {
+				s.implicitTLS = implicitTLS
+				s.tlsConfig = newTestTLSConfig(t)
+			})
+			emailConfig := server.config()
+			emailConfig.TLS = !implicitTLS
+
+			certErr := diagnose(t, emailConfig)
+			assert.EqualError(t, certErr, "tls: failed to verify certificate: x509: certificate signed by unknown authority")
+			var verificationErr *tls.CertificateVerificationError
+			assert.ErrorAs(t, certErr, &verificationErr)
+
+			diagnosis := certErr.Diagnosis
+			assert.Equal(t, "127.0.0.1", diagnosis.Host)
+			require.Len(t, diagnosis.Chain, 1)
+			assert.Equal(t, "not stapled", diagnosis.OCSP)
+			require.Len(t, diagnosis.Problems, 1)
+			assert.Equal(t, "the server certificate is self-signed", diagnosis.Problems[0].Problem)
+			assert.Equal(t, "add the certificate to caFile, or pin it with pinSha256: "+diagnosis.Chain[0].PinSHA256, diagnosis.Problems[0].Suggestion)
+		})
+	}
+}
+
+func TestDiagnoseHostnameMismatch(t *testing.T) {
+	ca := newTestCA(t, "Test Private CA")
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{ca.issue(t, x509.ExtKeyUsageServerAuth, "mail.example.com", "10.0.0.25")}}
+	})
+	emailConfig := server.config()
+	emailConfig.TLS = true
+	emailConfig.CAFile = writePEM(t, t.TempDir(), "ca.pem", certBlock(ca.cert.Raw))
+
+	diagnosis := diagnose(t, emailConfig).Diagnosis
+	assert.Equal(t, []ChainProblem{{
+		Problem:    "the server certificate is valid for mail.example.com, 10.0.0.25, not 127.0.0.1",
+		Suggestion: "set host to a name the certificate is valid for",
+	}}, diagnosis.Problems)
+}
+
+func TestDiagnoseMissingIntermediate(t *testing.T) {
+	root := newTestCA(t, "Test Root CA")
+	intermediate := root.intermediate(t, "Test Intermediate CA")
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.implicitTLS = true
+		s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{intermediate.issue(t, x509.ExtKeyUsageServerAuth, "127.0.0.1")}}
+	})
+	emailConfig := server.config()
+	emailConfig.CAFile = writePEM(t, t.TempDir(), "ca.pem", certBlock(root.cert.Raw))
+
+	diagnosis := diagnose(t, emailConfig).Diagnosis
+	assert.Equal(t, []ChainProblem{{
+		Problem:    "the chain ends at CN=127.0.0.1, whose issuer CN=Test Intermediate CA was not sent by the server and is not a trusted root",
+		Suggestion: "configure the server to send its intermediate certificates, or add CN=Test Intermediate CA to caFile if it is a private CA",
+	}}, diagnosis.Problems)
+}
+
+func TestDiagnoseUntrustedRootAndRevokedStaple(t *testing.T) {
+	root := newTestCA(t, "Test Root CA")
+	cert := root.issue(t, x509.ExtKeyUsageServerAuth, "127.0.0.1")
+	cert.Certificate = append(cert.Certificate, root.cert.Raw)
+	root.staple(t, &cert, ocsp.Revoked)
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	})
+	emailConfig := server.config()
+	emailConfig.TLS = true
+
+	diagnosis := diagnose(t, emailConfig).Diagnosis
+	assert.Len(t, diagnosis.Chain, 2)
+	assert.Equal(t, "revoked", diagnosis.OCSP)
+	assert.Equal(t, []string{
+		"the chain ends at the self-signed root CN=Test Root CA, which is not trusted",
+		"the server certificate was revoked on 2026-01-02T03:04:05Z",
+	}, problems(diagnosis))
+}
+
+func TestDiagnoseChain(t *testing.T) {
+	root := newTestCA(t, "Test Root CA")
+	other := newTestCA(t, "Other CA")
+	cert := root.issue(t, x509.ExtKeyUsageServerAuth, "127.0.0.1")
+	root.staple(t, &cert, ocsp.Good)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, other.cert, root.cert}, OCSPResponse: cert.OCSPStaple}
+
+	t.Run("expired and out of order", func(t *testing.T) {
+		later := leaf.NotAfter.Add(time.Hour)
+		diagnosis := diagnoseChain(state, "127.0.0.1", x509.CertificateInvalidError{Cert: leaf, Reason: x509.Expired}, later)
+		assert.Equal(t, []string{
+			"CN=127.0.0.1 expired on " + leaf.NotAfter.Format(time.RFC3339),
+			"CN=Other CA expired on " + other.cert.NotAfter.Format(time.RFC3339),
+			"CN=Test Root CA expired on " + root.cert.NotAfter.Format(time.RFC3339),
+			"CN=127.0.0.1 was not issued by the next certificate of the chain, CN=Other CA",
+			"CN=Other CA was not issued by the next certificate of the chain, CN=Test Root CA",
+		}, problems(diagnosis))
+		assert.Contains(t, diagnosis.OCSP, "invalid: bad OCSP signature", "the staple is checked against the next certificate")
+	})
+
+	t.Run("good staple", func(t *testing.T) {
+		state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, root.cert}, OCSPResponse: cert.OCSPStaple}
+		diagnosis := diagnoseChain(state, "127.0.0.1", x509.UnknownAuthorityError{}, time.Now())
+		assert.Regexp(t, `^good, next update \d{4}-\d{2}-\d{2}T`, diagnosis.OCSP)
+	})
+
+	t.Run("not yet valid", func(t *testing.T) {
+		earlier := root.cert.NotBefore.Add(-time.Hour)
+		diagnosis := diagnoseChain(tls.ConnectionState{PeerCertificates: []*x509.Certificate{root.cert}}, "Test Root CA", x509.CertificateInvalidError{}, earlier)
+		assert.Equal(t, ChainProblem{
+			Problem:    "CN=Test Root CA is not valid before " + root.cert.NotBefore.Format(time.RFC3339),
+			Suggestion: "check the clock of this machine and of the server",
+		}, diagnosis.Problems[0])
+		assert.Equal(t, "the server certificate is valid for Test Root CA (common name only, which is not checked), not Test Root CA", diagnosis.Problems[1].Problem)
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		diagnosis := diagnoseChain(tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}, "127.0.0.1",
+			x509.CertificateInvalidError{Cert: leaf, Reason: x509.IncompatibleUsage}, time.Now())
+		assert.Equal(t, []ChainProblem{{
+			Problem:    "x509: certificate specifies an incompatible key usage",
+			Suggestion: "set verifyCertificate: false to skip verification while testing",
+		}}, diagnosis.Problems)
+	})
+}
@@ -0,0 +1,65 @@
+package smtpclient
+
+import (
+	"errors"
+	"net/textproto"
+)
+
+// Phase names the step of the SMTP transaction an error happened in.
+type Phase string
+
+const (
+	PhaseConnect  Phase = "connect"
+	PhaseEhlo     Phase = "ehlo"
+	PhaseStartTLS Phase = "starttls"
+	PhaseAuth     Phase = "auth"
+	PhaseMail     Phase = "mail"
+	PhaseRcpt     Phase = "rcpt"
+	PhaseData     Phase = "data"
+)
+
+var (
+	ErrSSLAndTLS            = errors.New("invalid configuration: both SSL and TLS (STARTTLS) are enabled; choose only one")
+	ErrStartTLSNotSupported = errors.New("server does not support STARTTLS")
+	ErrNotConnected         = errors.New("client is not connected")
+	ErrPinMismatch          = errors.New("no certificate of the server matches pinSha256")
+)
+
+// Error is returned when a step of the SMTP transaction fails.
+// Code and Enhanced hold the SMTP reply and RFC 3463 status codes when the failure was a server reply.
+type Error struct {
+	Phase    Phase
+	Code     int
+	Enhanced string
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return string(e.Phase) + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(phase Phase, err error) *Error {
+	e := &Error{Phase: phase, Err: err}
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		reply := newReply(protoErr.Code, protoErr.Msg)
+		e.Code, e.Enhanced = reply.Code, reply.Enhanced
+	}
+	return e
+}
+
+// replyOf returns the server reply behind a command outcome, or nil when there was none.
+func replyOf(code int, msg string, err error) *Reply {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return newReply(protoErr.Code, protoErr.Msg)
+	}
+	if err == nil && code != 0 {
+		return newReply(code, msg)
+	}
+	return nil
+}
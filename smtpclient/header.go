@@ -0,0 +1,88 @@
+package smtpclient
+
+import (
+	"fmt"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Headers that shape the message or its recipients. Custom headers may only
+// replace them with OverrideHeaders.
+var structuralHeaders = map[string]bool{
+	"From":                      true,
+	"Sender":                    true,
+	"To":                        true,
+	"Cc":                        true,
+	"Reply-To":                  true,
+	"Subject":                   true,
+	"Return-Path":               true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Content-Disposition":       true,
+}
+
+// validateHeaders rejects custom headers that could inject other headers or
+// silently replace structural ones.
+func validateHeaders(emailConfig *EmailConfig) error {
+	seen := map[string]string{}
+	for _, name := range sortedHeaderNames(emailConfig.Headers) {
+		value := emailConfig.Headers[name]
+		if err := validateHeaderName(name); err != nil {
+			return err
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("invalid header %q: the value must not contain CR or LF", name)
+		}
+		// Names are case-insensitive, so compare them in canonical form
+		key := textproto.CanonicalMIMEHeaderKey(name)
+		if previous, ok := seen[key]; ok {
+			return fmt.Errorf("invalid header %q: it duplicates %q", name, previous)
+		}
+		seen[key] = name
+		if key == "Bcc" {
+			return fmt.Errorf("invalid header %q: use bcc, which never writes the recipients into the headers", name)
+		}
+		if structuralHeaders[key] && !emailConfig.OverrideHeaders {
+			return fmt.Errorf("invalid header %q: it is set by gomtp; enable overrideHeaders to replace it", name)
+		}
+	}
+	return nil
+}
+
+// validateHeaderName accepts the printable ASCII characters except colon, as RFC 5322 does.
+func validateHeaderName(name string) error {
+	if name == "" {
+		return fmt.Errorf("invalid header: the name is empty")
+	}
+	for _, r := range name {
+		if r < 33 || r > 126 || r == ':' {
+			return fmt.Errorf("invalid header %q: the name may only contain printable ASCII characters except colon", name)
+		}
+	}
+	return nil
+}
+
+// setCustomHeaders adds the validated custom headers to m, in name order. Names are written
+// as given, except the headers gomtp or gomail write themselves, which are replaced under their own key.
+func setCustomHeaders(m *gomail.Message, headers map[string]string) {
+	for _, name := range sortedHeaderNames(headers) {
+		key := name
+		if canonical := textproto.CanonicalMIMEHeaderKey(name); structuralHeaders[canonical] || canonical == "Date" {
+			key = canonical
+		}
+		m.SetHeader(key, headers[name])
+	}
+}
+
+func sortedHeaderNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
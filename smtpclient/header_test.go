@@ -0,0 +1,79 @@
+package smtpclient
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendCustomHeaders(t *testing.T) {
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	emailConfig.Headers = map[string]string{
+		"X-Priority":       "1",
+		"list-unsubscribe": "<mailto:unsubscribe@example.com>",
+		"Message-ID":       "<test-1@example.com>",
+		"In-Reply-To":      "<test-0@example.com>",
+		"date":             "Mon, 02 Jan 2006 15:04:05 +0000",
+	}
+
+	require.NoError(t, send(t, emailConfig))
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0].Data, "X-Priority: 1\n")
+	assert.Contains(t, messages[0].Data, "\nlist-unsubscribe: <mailto:unsubscribe@example.com>\n", "names are written as given")
+	assert.Contains(t, messages[0].Data, "\nMessage-ID: <test-1@example.com>\n")
+	assert.Contains(t, messages[0].Data, "In-Reply-To: <test-0@example.com>\n")
+	assert.Contains(t, messages[0].Data, "\nDate: Mon, 02 Jan 2006 15:04:05 +0000\n", "the date gomail writes is replaced")
+	assert.Equal(t, 1, strings.Count(strings.ToLower(messages[0].Data), "\ndate: "))
+}
+
+func TestOverrideStructuralHeaders(t *testing.T) {
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	emailConfig.Subject = "Configured subject"
+	emailConfig.Headers = map[string]string{"Subject": "Overridden subject"}
+
+	_, err := NewMessage(emailConfig)
+	assert.EqualError(t, err, `invalid header "Subject": it is set by gomtp; enable overrideHeaders to replace it`)
+
+	emailConfig.OverrideHeaders = true
+	require.NoError(t, send(t, emailConfig))
+	messages := server.received()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0].Data, "Subject: Overridden subject\n")
+	assert.NotContains(t, messages[0].Data, "Configured subject")
+}
+
+func TestInvalidHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		err     string
+	}{
+		{"injection in value", map[string]string{"X-Test": "a\r\nBcc: victim@example.com"}, `invalid header "X-Test": the value must not contain CR or LF`},
+		{"injection in name", map[string]string{"X-Test\r\nBcc": "a"}, "the name may only contain printable ASCII characters except colon"},
+		{"colon in name", map[string]string{"X-Test:": "a"}, "the name may only contain printable ASCII characters except colon"},
+		{"space in name", map[string]string{"X Test": "a"}, "the name may only contain printable ASCII characters except colon"},
+		{"empty name", map[string]string{"": "a"}, "invalid header: the name is empty"},
+		{"structural header", map[string]string{"content-type": "text/html"}, `invalid header "content-type": it is set by gomtp`},
+		{"duplicate name", map[string]string{"X-Test": "a", "x-test": "b"}, `invalid header "x-test": it duplicates "X-Test"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emailConfig := &EmailConfig{From: "from@example.com", Headers: tt.headers}
+			_, err := NewMessage(emailConfig)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestBccHeaderIsNeverAllowed(t *testing.T) {
+	emailConfig := &EmailConfig{From: "from@example.com", Headers: map[string]string{"BCC": "hidden@example.com"}, OverrideHeaders: true}
+	_, err := NewMessage(emailConfig)
+	assert.EqualError(t, err, `invalid header "BCC": use bcc, which never writes the recipients into the headers`)
+}
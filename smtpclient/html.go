@@ -0,0 +1,57 @@
+package smtpclient
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlCommentPattern = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlHiddenPattern  = regexp.MustCompile(`(?is)<(?:head|script|style)\b.*?</(?:head|script|style)\s*>`)
+	htmlLinkPattern    = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a\s*>`)
+	htmlBreakPattern   = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlItemPattern    = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlBlockPattern   = regexp.MustCompile(`(?i)</?(?:p|div|h[1-6]|ul|ol|table|tr|blockquote|pre|hr)\b[^>]*>`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// htmlToText renders an HTML body as readable plain text for the text/plain alternative.
+// Links keep their target in parentheses and list items become "- " lines.
+func htmlToText(body string) string {
+	text := htmlCommentPattern.ReplaceAllString(body, "")
+	text = htmlHiddenPattern.ReplaceAllString(text, "")
+	text = htmlLinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		m := htmlLinkPattern.FindStringSubmatch(link)
+		label := strings.TrimSpace(htmlTagPattern.ReplaceAllString(m[2], ""))
+		if label == "" || label == m[1] {
+			return m[1]
+		}
+		return label + " (" + m[1] + ")"
+	})
+
+	// Line breaks in the source are insignificant; the markup decides where lines end
+	text = whitespacePattern.ReplaceAllString(text, " ")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlItemPattern.ReplaceAllString(text, "\n- ")
+	text = htmlBlockPattern.ReplaceAllString(text, "\n\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,35 @@
+package smtpclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLToText(t *testing.T) {
+	body := `<html>
+<head><title>Alert</title><style>p { color: red; }</style></head>
+<body>
+  <!-- generated by the monitor -->
+  <h1>Disk   almost
+  full</h1>
+  <p>Usage on <b>db-1</b> is at 92%.<br>Free space: 8&nbsp;GB &amp; falling.</p>
+  <ul><li>Check <a href="https://example.com/runbook">the runbook</a></li><li><a href="https://example.com">https://example.com</a></li></ul>
+  <script>alert("hidden")</script>
+</body>
+</html>`
+
+	expected := "Disk almost full\n" +
+		"\n" +
+		"Usage on db-1 is at 92%.\n" +
+		"Free space: 8 GB & falling.\n" +
+		"\n" +
+		"- Check the runbook (https://example.com/runbook)\n" +
+		"- https://example.com"
+	assert.Equal(t, expected, htmlToText(body))
+}
+
+func TestHTMLToTextPlainInput(t *testing.T) {
+	assert.Equal(t, "no markup here", htmlToText("  no markup\nhere "))
+	assert.Equal(t, "", htmlToText(""))
+}
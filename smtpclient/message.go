@@ -0,0 +1,70 @@
+package smtpclient
+
+import (
+	"net/mail"
+
+	"gopkg.in/gomail.v2"
+)
+
+// NewMessage creates the email message described by the config.
+// Bcc recipients are never written into the headers; Send adds them to the envelope only,
+// just like envelopeFrom, which replaces From in MAIL FROM.
+// It fails when an address or a custom header is invalid, or an attachment cannot be resolved.
+func NewMessage(emailConfig *EmailConfig) (*gomail.Message, error) {
+	if err := ValidateAddresses(emailConfig); err != nil {
+		return nil, err
+	}
+	if err := validateHeaders(emailConfig); err != nil {
+		return nil, err
+	}
+	attachments, err := ResolveAttachments(emailConfig.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	m := gomail.NewMessage()
+	setAddressHeader(m, "From", emailConfig.From)
+	setAddressHeader(m, "Sender", emailConfig.Sender)
+	setAddressHeader(m, "To", emailConfig.To...)
+	setAddressHeader(m, "Cc", emailConfig.CcList...)
+	setAddressHeader(m, "Reply-To", emailConfig.ReplyTo...)
+	m.SetHeader("Subject", emailConfig.Subject)
+	setCustomHeaders(m, emailConfig.Headers)
+	setBody(m, emailConfig)
+	for _, file := range attachments {
+		file.attach(m)
+	}
+	return m, nil
+}
+
+// setAddressHeader sets the header to the validated addresses, encoding non-ASCII display names.
+// Empty addresses are skipped and the header is left out when none remain.
+func setAddressHeader(m *gomail.Message, header string, addresses ...string) {
+	var formatted []string
+	for _, address := range addresses {
+		if a, err := mail.ParseAddress(address); err == nil {
+			formatted = append(formatted, m.FormatAddress(a.Address, a.Name))
+		}
+	}
+	if len(formatted) > 0 {
+		m.SetHeader(header, formatted...)
+	}
+}
+
+// setBody sets the text body, the HTML body, or both as multipart/alternative.
+// With TextFromHTML the text part is generated when only HTML is given.
+func setBody(m *gomail.Message, emailConfig *EmailConfig) {
+	text := emailConfig.Body
+	if text == "" && emailConfig.TextFromHTML {
+		text = htmlToText(emailConfig.BodyHTML)
+	}
+	switch {
+	case emailConfig.BodyHTML == "":
+		m.SetBody("text/plain", text)
+	case text == "":
+		m.SetBody("text/html", emailConfig.BodyHTML)
+	default:
+		m.SetBody("text/plain", text)
+		m.AddAlternative("text/html", emailConfig.BodyHTML)
+	}
+}
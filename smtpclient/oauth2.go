@@ -0,0 +1,81 @@
+package smtpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OAuth2Config holds the client credentials used to exchange a refresh token for an access token.
+type OAuth2Config struct {
+	ClientID     string   `yaml:"clientId"`
+	ClientSecret string   `yaml:"clientSecret"`
+	RefreshToken string   `yaml:"refreshToken"`
+	TokenURL     string   `yaml:"tokenUrl"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// Enabled reports whether an access token should be fetched from the token endpoint.
+func (o OAuth2Config) Enabled() bool {
+	return o.RefreshToken != ""
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// fetchAccessToken performs the OAuth2 refresh_token grant against the token endpoint.
+func fetchAccessToken(ctx context.Context, httpClient *http.Client, oauth2Config OAuth2Config) (string, error) {
+	if oauth2Config.TokenURL == "" {
+		return "", errors.New("invalid configuration: oauth2.tokenUrl is required with oauth2.refreshToken")
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {oauth2Config.RefreshToken},
+		"client_id":     {oauth2Config.ClientID},
+	}
+	if oauth2Config.ClientSecret != "" {
+		form.Set("client_secret", oauth2Config.ClientSecret)
+	}
+	if len(oauth2Config.Scopes) > 0 {
+		form.Set("scope", strings.Join(oauth2Config.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, oauth2Config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oauth2 token refresh: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("oauth2 token refresh: %s: invalid response: %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		reason := token.Error
+		if token.ErrorDescription != "" {
+			reason += ": " + token.ErrorDescription
+		}
+		return "", fmt.Errorf("oauth2 token refresh: %s: %s", resp.Status, reason)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("oauth2 token refresh: response contains no access_token")
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,119 @@
+package smtpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenEndpoint starts a fake OAuth2 token endpoint that trades refreshToken for accessToken.
+func newTokenEndpoint(t *testing.T, refreshToken, accessToken string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPost || r.FormValue("grant_type") != "refresh_token" ||
+			r.FormValue("client_id") != "client-id" || r.FormValue("client_secret") != "client-secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request"})
+			return
+		}
+		if r.FormValue("refresh_token") != refreshToken {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "Token has been expired or revoked."})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"access_token": accessToken, "token_type": "Bearer", "expires_in": 3599})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func oauth2EmailConfig(server *fakeServer, tokenURL, auth string) *EmailConfig {
+	emailConfig := server.config()
+	emailConfig.Auth = auth
+	emailConfig.Username = "user@example.com"
+	emailConfig.OAuth2 = OAuth2Config{
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		RefreshToken: "refresh-token",
+		TokenURL:     tokenURL,
+	}
+	return emailConfig
+}
+
+func newOAuth2Server(t *testing.T, mechanisms ...string) *fakeServer {
+	return newFakeServer(t, func(s *fakeServer) {
+		s.authMechanisms = mechanisms
+		s.username = "user@example.com"
+		s.password = "access-token"
+	})
+}
+
+func TestOAuth2RefreshToken(t *testing.T) {
+	endpoint := newTokenEndpoint(t, "refresh-token", "access-token")
+	for _, mechanism := range []string{AuthXOAuth2, AuthOAuthBearer} {
+		t.Run(mechanism, func(t *testing.T) {
+			server := newOAuth2Server(t, "XOAUTH2", "OAUTHBEARER")
+			require.NoError(t, send(t, oauth2EmailConfig(server, endpoint.URL, mechanism)))
+			assert.Equal(t, mechanism, server.authMechanism())
+			assert.Len(t, server.received(), 1)
+		})
+	}
+}
+
+func TestOAuth2AutoPrefersOAuthBearer(t *testing.T) {
+	endpoint := newTokenEndpoint(t, "refresh-token", "access-token")
+	server := newOAuth2Server(t, "PLAIN", "LOGIN", "XOAUTH2", "OAUTHBEARER")
+	require.NoError(t, send(t, oauth2EmailConfig(server, endpoint.URL, AuthAuto)))
+	assert.Equal(t, AuthOAuthBearer, server.authMechanism())
+}
+
+func TestOAuth2AutoFallsBackToXOAuth2(t *testing.T) {
+	endpoint := newTokenEndpoint(t, "refresh-token", "access-token")
+	server := newOAuth2Server(t, "PLAIN", "LOGIN", "XOAUTH2")
+	require.NoError(t, send(t, oauth2EmailConfig(server, endpoint.URL, AuthAuto)))
+	assert.Equal(t, AuthXOAuth2, server.authMechanism())
+}
+
+func TestOAuth2RevokedRefreshToken(t *testing.T) {
+	endpoint := newTokenEndpoint(t, "another-refresh-token", "access-token")
+	server := newOAuth2Server(t, "XOAUTH2")
+
+	err := send(t, oauth2EmailConfig(server, endpoint.URL, AuthXOAuth2))
+	var smtpErr *Error
+	require.ErrorAs(t, err, &smtpErr)
+	assert.Equal(t, PhaseAuth, smtpErr.Phase)
+	assert.Contains(t, err.Error(), "invalid_grant: Token has been expired or revoked.")
+	assert.Empty(t, server.received())
+}
+
+func TestOAuth2RejectedAccessToken(t *testing.T) {
+	endpoint := newTokenEndpoint(t, "refresh-token", "stale-access-token")
+	for _, mechanism := range []string{AuthXOAuth2, AuthOAuthBearer} {
+		server := newOAuth2Server(t, "XOAUTH2", "OAUTHBEARER")
+		err := send(t, oauth2EmailConfig(server, endpoint.URL, mechanism))
+		var smtpErr *Error
+		require.ErrorAs(t, err, &smtpErr, mechanism)
+		assert.Equal(t, 535, smtpErr.Code, mechanism)
+	}
+}
+
+func TestOAuth2MissingTokenURL(t *testing.T) {
+	server := newOAuth2Server(t, "XOAUTH2")
+	err := send(t, oauth2EmailConfig(server, "", AuthXOAuth2))
+	assert.ErrorContains(t, err, "oauth2.tokenUrl is required")
+}
+
+func TestXOAuth2WithAccessTokenAsPassword(t *testing.T) {
+	server := newOAuth2Server(t, "XOAUTH2")
+	emailConfig := server.config()
+	emailConfig.Auth = AuthXOAuth2
+	emailConfig.Username = "user@example.com"
+	emailConfig.Password = "access-token"
+
+	require.NoError(t, send(t, emailConfig))
+	assert.Equal(t, AuthXOAuth2, server.authMechanism())
+}
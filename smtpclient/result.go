@@ -0,0 +1,179 @@
+package smtpclient
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Reply is a server reply to one SMTP command.
+type Reply struct {
+	Code     int    `json:"code"`
+	Enhanced string `json:"enhancedCode,omitempty"`
+	Message  string `json:"message"`
+}
+
+// PhaseResult is the outcome of one step of the transaction.
+type PhaseResult struct {
+	Phase    Phase         `json:"phase"`
+	Reply    *Reply        `json:"reply,omitempty"`
+	Duration time.Duration `json:"-"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func (p PhaseResult) MarshalJSON() ([]byte, error) {
+	type phaseResult PhaseResult
+	return json.Marshal(struct {
+		phaseResult
+		DurationMs float64 `json:"durationMs"`
+	}{phaseResult(p), milliseconds(p.Duration)})
+}
+
+// RecipientResult tells whether the server accepted a RCPT TO address.
+type RecipientResult struct {
+	Address  string `json:"address"`
+	Accepted bool   `json:"accepted"`
+	Reply    *Reply `json:"reply,omitempty"`
+}
+
+// Steps reported in Result.Timings.
+const (
+	StepDNS      = "dns"
+	StepTCP      = "tcp"
+	StepTLS      = "tls"
+	StepGreeting = "greeting"
+	StepEhlo     = "ehlo"
+	StepStartTLS = "starttls"
+	StepAuth     = "auth"
+	StepMail     = "mail"
+	StepRcpt     = "rcpt"
+	StepData     = "data"
+)
+
+// Timing is the latency of one step of the session, such as resolving the host or a single RCPT.
+type Timing struct {
+	Step     string        `json:"step"`
+	Target   string        `json:"target,omitempty"`
+	Duration time.Duration `json:"-"`
+}
+
+func (t Timing) MarshalJSON() ([]byte, error) {
+	type timing Timing
+	return json.Marshal(struct {
+		timing
+		DurationMs float64 `json:"durationMs"`
+	}{timing(t), milliseconds(t.Duration)})
+}
+
+// CertificateInfo summarizes a certificate presented by the server.
+type CertificateInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	DNSNames  []string  `json:"dnsNames,omitempty"`
+	PinSHA256 string    `json:"pinSha256"`
+}
+
+// TLSInfo describes the negotiated TLS connection.
+type TLSInfo struct {
+	Version      string            `json:"version"`
+	CipherSuite  string            `json:"cipherSuite"`
+	ServerName   string            `json:"serverName"`
+	Certificates []CertificateInfo `json:"certificates,omitempty"`
+}
+
+// ExpiringCertificates returns the certificates of the chain that are expired or expire within the window.
+func (info *TLSInfo) ExpiringCertificates(window time.Duration) []CertificateInfo {
+	var expiring []CertificateInfo
+	deadline := time.Now().Add(window)
+	for _, cert := range info.Certificates {
+		if cert.NotAfter.Before(deadline) {
+			expiring = append(expiring, cert)
+		}
+	}
+	return expiring
+}
+
+// Result records what happened during Dial and Send.
+type Result struct {
+	Success    bool              `json:"success"`
+	Phase      Phase             `json:"phase"`
+	Error      string            `json:"error,omitempty"`
+	Phases     []PhaseResult     `json:"phases"`
+	Recipients []RecipientResult `json:"recipients,omitempty"`
+	QueueID    string            `json:"queueId,omitempty"`
+	TLS        *TLSInfo          `json:"tls,omitempty"`
+	// CertificateDiagnosis explains why the certificate chain of the server failed verification.
+	CertificateDiagnosis *ChainDiagnosis `json:"certificateDiagnosis,omitempty"`
+	Timings              []Timing        `json:"timings"`
+	Duration             time.Duration   `json:"-"`
+}
+
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(struct {
+		result
+		DurationMs float64 `json:"durationMs"`
+	}{result(r), milliseconds(r.Duration)})
+}
+
+func newTLSInfo(state tls.ConnectionState) *TLSInfo {
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+	}
+	for _, cert := range state.PeerCertificates {
+		info.Certificates = append(info.Certificates, CertificateInfo{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			DNSNames:  cert.DNSNames,
+			PinSHA256: SPKIPin(cert),
+		})
+	}
+	return info
+}
+
+var enhancedCodePattern = regexp.MustCompile(`^([245]\.\d{1,3}\.\d{1,3})(?:\s+|$)`)
+
+// newReply splits the RFC 3463 enhanced status code off the reply text.
+func newReply(code int, msg string) *Reply {
+	reply := &Reply{Code: code, Message: msg}
+	if m := enhancedCodePattern.FindStringSubmatch(msg); m != nil {
+		reply.Enhanced = m[1]
+		reply.Message = msg[len(m[0]):]
+	}
+	return reply
+}
+
+// Patterns for the queue IDs reported by common MTAs in the final DATA reply.
+var queueIDPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`queued as ([0-9A-Za-z._-]+)`),                   // Postfix, mailpit
+	regexp.MustCompile(`\bid=([0-9A-Za-z-]+)`),                          // Exim
+	regexp.MustCompile(`^OK\s+\d+\s+(\S+)\s+- gsmtp`),                   // Gmail
+	regexp.MustCompile(`InternalId=(\d+)`),                              // Microsoft 365
+	regexp.MustCompile(`^([0-9A-Za-z]+) Message accepted for delivery`), // Sendmail
+}
+
+// ParseQueueID extracts the queue ID from the text of the final DATA reply, or returns "".
+func ParseQueueID(msg string) string {
+	msg = strings.TrimSpace(msg)
+	if m := enhancedCodePattern.FindString(msg); m != "" {
+		msg = msg[len(m):]
+	}
+	for _, pattern := range queueIDPatterns {
+		if m := pattern.FindStringSubmatch(msg); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
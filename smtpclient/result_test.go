@@ -0,0 +1,241 @@
+package smtpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultOfSuccessfulSend(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = newTestTLSConfig(t)
+	})
+	emailConfig := server.config()
+	emailConfig.TLS = true
+	emailConfig.CcList = []string{"cc@example.com"}
+
+	client := NewClient(emailConfig)
+	ctx := context.Background()
+	require.NoError(t, client.Dial(ctx))
+	defer client.Close()
+	require.NoError(t, client.Send(ctx, newMessage(t, emailConfig)))
+
+	result := client.Result()
+	assert.True(t, result.Success)
+	assert.Equal(t, PhaseData, result.Phase)
+	assert.Empty(t, result.Error)
+	assert.Equal(t, "FAKE1", result.QueueID)
+
+	var phases []Phase
+	for _, p := range result.Phases {
+		phases = append(phases, p.Phase)
+		require.NotNil(t, p.Reply, p.Phase)
+	}
+	assert.Equal(t, []Phase{PhaseConnect, PhaseEhlo, PhaseStartTLS, PhaseEhlo, PhaseMail, PhaseRcpt, PhaseRcpt, PhaseData}, phases)
+	assert.Equal(t, &Reply{Code: 220, Enhanced: "2.0.0", Message: "Ready to start TLS"}, result.Phases[2].Reply)
+	assert.Equal(t, &Reply{Code: 250, Enhanced: "2.0.0", Message: "Ok: queued as FAKE1"}, result.Phases[7].Reply)
+
+	assert.Equal(t, []RecipientResult{
+		{Address: "to@example.com", Accepted: true, Reply: &Reply{Code: 250, Enhanced: "2.1.5", Message: "Ok"}},
+		{Address: "cc@example.com", Accepted: true, Reply: &Reply{Code: 250, Enhanced: "2.1.5", Message: "Ok"}},
+	}, result.Recipients)
+
+	require.NotNil(t, result.TLS)
+	assert.Equal(t, "TLS 1.3", result.TLS.Version)
+	assert.Len(t, result.TLS.Certificates, 1)
+}
+
+func TestResultOfRejectedRecipient(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.rejectRcpt["to@example.com"] = true
+	})
+	emailConfig := server.config()
+	emailConfig.CcList = []string{"cc@example.com"}
+
+	client := NewClient(emailConfig)
+	ctx := context.Background()
+	require.NoError(t, client.Dial(ctx))
+	defer client.Close()
+
+	err := client.Send(ctx, newMessage(t, emailConfig))
+	var smtpErr *Error
+	require.ErrorAs(t, err, &smtpErr)
+	assert.Equal(t, "5.1.1", smtpErr.Enhanced)
+
+	result := client.Result()
+	assert.False(t, result.Success)
+	assert.Equal(t, PhaseRcpt, result.Phase)
+	assert.Equal(t, err.Error(), result.Error)
+	require.Len(t, result.Recipients, 2, "every recipient is tried")
+	assert.False(t, result.Recipients[0].Accepted)
+	assert.Equal(t, 550, result.Recipients[0].Reply.Code)
+	assert.True(t, result.Recipients[1].Accepted)
+	assert.Empty(t, server.received())
+}
+
+func TestResultOfSecondSend(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.rejectRcpt["rejected@example.com"] = true
+	})
+	emailConfig := server.config()
+	emailConfig.To = AddressList{"rejected@example.com"}
+
+	var trace bytes.Buffer
+	client := NewClient(emailConfig)
+	client.Trace = &trace
+	ctx := context.Background()
+	require.NoError(t, client.Dial(ctx))
+	defer client.Close()
+
+	require.Error(t, client.Send(ctx, newMessage(t, emailConfig)))
+	first := client.Result()
+	assert.Contains(t, trace.String(), "C: RSET\n", "the rejected transaction is aborted")
+
+	emailConfig.To = AddressList{"to@example.com"}
+	require.NoError(t, client.Send(ctx, newMessage(t, emailConfig)))
+
+	result := client.Result()
+	assert.True(t, result.Success)
+	assert.Empty(t, result.Error)
+	assert.Equal(t, "FAKE1", result.QueueID)
+	var phases []Phase
+	for _, p := range result.Phases {
+		phases = append(phases, p.Phase)
+	}
+	assert.Equal(t, []Phase{PhaseConnect, PhaseEhlo, PhaseMail, PhaseRcpt, PhaseData}, phases)
+	assert.Equal(t, []RecipientResult{
+		{Address: "to@example.com", Accepted: true, Reply: &Reply{Code: 250, Enhanced: "2.1.5", Message: "Ok"}},
+	}, result.Recipients)
+
+	assert.Equal(t, "rejected@example.com", first.Recipients[0].Address, "the previous result is left as it was")
+	assert.Equal(t, PhaseRcpt, first.Phases[len(first.Phases)-1].Phase)
+	require.Len(t, server.received(), 1)
+}
+
+func TestResultOfFailedConnect(t *testing.T) {
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	server.listener.Close()
+
+	client := NewClient(emailConfig)
+	err := client.Dial(context.Background())
+	require.Error(t, err)
+
+	result := client.Result()
+	assert.Equal(t, PhaseConnect, result.Phase)
+	require.Len(t, result.Phases, 1)
+	assert.NotEmpty(t, result.Phases[0].Error)
+	assert.Nil(t, result.Phases[0].Reply)
+}
+
+func TestResultTimings(t *testing.T) {
+	tests := []struct {
+		name        string
+		implicitTLS bool
+		startTLS    bool
+		steps       []string
+	}{
+		{"plain", false, false, []string{StepDNS, StepTCP, StepGreeting, StepEhlo, StepMail, StepRcpt, StepRcpt, StepData}},
+		{"starttls", false, true, []string{StepDNS, StepTCP, StepGreeting, StepEhlo, StepStartTLS, StepTLS, StepEhlo, StepMail, StepRcpt, StepRcpt, StepData}},
+		{"implicit tls", true, false, []string{StepDNS, StepTCP, StepTLS, StepGreeting, StepEhlo, StepMail, StepRcpt, StepRcpt, StepData}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(s *fakeServer) {
+				s.tlsConfig = newTestTLSConfig(t)
+				s.implicitTLS = tt.implicitTLS
+			})
+			emailConfig := server.config()
+			emailConfig.TLS = tt.startTLS
+			emailConfig.CcList = []string{"cc@example.com"}
+
+			client := NewClient(emailConfig)
+			ctx := context.Background()
+			require.NoError(t, client.Dial(ctx))
+			defer client.Close()
+			require.NoError(t, client.Send(ctx, newMessage(t, emailConfig)))
+
+			result := client.Result()
+			var steps []string
+			var total time.Duration
+			for _, timing := range result.Timings {
+				steps = append(steps, timing.Step)
+				total += timing.Duration
+			}
+			assert.Equal(t, tt.steps, steps)
+			assert.Equal(t, emailConfig.Host, result.Timings[0].Target)
+			assert.Equal(t, net.JoinHostPort(emailConfig.Host, strconv.Itoa(emailConfig.Port)), result.Timings[1].Target)
+			assert.Equal(t, "cc@example.com", result.Timings[len(steps)-2].Target)
+			assert.LessOrEqual(t, total, result.Duration)
+		})
+	}
+}
+
+func TestResultTimingsOfAuth(t *testing.T) {
+	server := newAuthServer(t, AuthPlain)
+	client := NewClient(authConfig(server, AuthAuto))
+	require.NoError(t, client.Dial(context.Background()))
+	defer client.Close()
+
+	timings := client.Result().Timings
+	last := timings[len(timings)-1]
+	assert.Equal(t, StepAuth, last.Step)
+	assert.Equal(t, AuthPlain, last.Target)
+}
+
+func TestResultJSON(t *testing.T) {
+	result := Result{
+		Success: true,
+		Phase:   PhaseData,
+		Phases:  []PhaseResult{{Phase: PhaseData, Reply: &Reply{Code: 250, Message: "Ok"}, Duration: 1500000}},
+		QueueID: "ABC",
+		Timings: []Timing{{Step: StepData, Duration: 1500000}},
+	}
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"success": true,
+		"phase": "data",
+		"phases": [{"phase": "data", "reply": {"code": 250, "message": "Ok"}, "durationMs": 1.5}],
+		"queueId": "ABC",
+		"timings": [{"step": "data", "durationMs": 1.5}],
+		"durationMs": 0
+	}`, string(data))
+}
+
+func TestParseQueueID(t *testing.T) {
+	tests := map[string]string{
+		"2.0.0 Ok: queued as 4TxyZ12abcz": "4TxyZ12abcz",
+		"OK id=1rAbCd-000aBc-Xy":          "1rAbCd-000aBc-Xy",
+		"2.0.0 OK  1700000000 d9443c01a7336-1cc5d1b0d43si1234567pld.123 - gsmtp":                   "d9443c01a7336-1cc5d1b0d43si1234567pld.123",
+		"2.6.0 <abc@example.com> [InternalId=12345678901234, Hostname=X] Queued mail for delivery": "12345678901234",
+		"2.0.0 u8ABCD012345 Message accepted for delivery":                                         "u8ABCD012345",
+		"2.0.0 Ok": "",
+	}
+	for msg, expected := range tests {
+		assert.Equal(t, expected, ParseQueueID(msg), msg)
+	}
+}
+
+func TestExpiringCertificates(t *testing.T) {
+	now := time.Now()
+	info := &TLSInfo{Certificates: []CertificateInfo{
+		{Subject: "CN=mail.example.com", NotAfter: now.Add(5 * 24 * time.Hour)},
+		{Subject: "CN=Intermediate", NotAfter: now.Add(365 * 24 * time.Hour)},
+		{Subject: "CN=Expired", NotAfter: now.Add(-time.Hour)},
+	}}
+
+	var subjects []string
+	for _, cert := range info.ExpiringCertificates(14 * 24 * time.Hour) {
+		subjects = append(subjects, cert.Subject)
+	}
+	assert.Equal(t, []string{"CN=mail.example.com", "CN=Expired"}, subjects)
+	assert.Len(t, info.ExpiringCertificates(time.Minute), 1)
+}
@@ -0,0 +1,192 @@
+package smtpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Scan modes reported in TLSScan.Mode.
+const (
+	ScanImplicitTLS = "implicit"
+	ScanStartTLS    = "starttls"
+)
+
+// Grades of a TLS version, a cipher suite or a whole scan, from best to worst.
+const (
+	GradeA = "A"
+	GradeB = "B"
+	GradeF = "F"
+)
+
+// scanTimeout bounds each handshake of a scan, so a server that stalls does not stall the scan.
+const scanTimeout = 10 * time.Second
+
+// ErrScanNeedsTLS is returned by ScanTLS for a config that uses neither ssl nor tls.
+var ErrScanNeedsTLS = errors.New("tls-scan needs ssl or tls to be enabled")
+
+// TLSScan lists the TLS versions and cipher suites a server accepts in one mode.
+type TLSScan struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	Mode string `json:"mode"`
+	// Grade is the worst grade of the accepted versions and suites.
+	Grade    string           `json:"grade,omitempty"`
+	Error    string           `json:"error,omitempty"`
+	Versions []TLSVersionScan `json:"versions"`
+}
+
+// TLSVersionScan tells whether the server accepts a TLS version and with which cipher suites.
+type TLSVersionScan struct {
+	Version    string `json:"version"`
+	Accepted   bool   `json:"accepted"`
+	Deprecated bool   `json:"deprecated"`
+	Grade      string `json:"grade,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// CipherSuites has every suite tried for TLS 1.0 to 1.2. TLS 1.3 suites cannot be
+	// offered one at a time, so only the negotiated one is listed.
+	CipherSuites []CipherSuiteScan `json:"cipherSuites,omitempty"`
+}
+
+// CipherSuiteScan tells whether the server accepts a cipher suite.
+type CipherSuiteScan struct {
+	Name     string `json:"name"`
+	Accepted bool   `json:"accepted"`
+	Grade    string `json:"grade"`
+	Weakness string `json:"weakness,omitempty"`
+}
+
+// ScanTLS connects to the server once per TLS version, and once per cipher suite of every
+// accepted version up to TLS 1.2, using implicit TLS or STARTTLS as configured. Only the
+// host, port, mode and client certificate of the config are used; certificates are not
+// verified, so the scan reports the protocol even for servers with an untrusted certificate.
+// Failing to reach the server is reported in TLSScan.Error.
+func ScanTLS(ctx context.Context, emailConfig *EmailConfig) (*TLSScan, error) {
+	if !emailConfig.SSL && !emailConfig.TLS {
+		return nil, ErrScanNeedsTLS
+	}
+	if emailConfig.SSL && emailConfig.TLS {
+		return nil, ErrSSLAndTLS
+	}
+	base := EmailConfig{
+		Host:       emailConfig.Host,
+		Port:       emailConfig.Port,
+		SSL:        emailConfig.SSL,
+		TLS:        emailConfig.TLS,
+		Auth:       AuthNone,
+		ClientCert: emailConfig.ClientCert,
+		ClientKey:  emailConfig.ClientKey,
+		// The scan is about the protocol, not the certificate
+		VerifyCertificate: new(bool),
+	}
+	scan := &TLSScan{Host: base.Host, Port: base.Port, Mode: ScanStartTLS, Versions: []TLSVersionScan{}}
+	if base.SSL {
+		scan.Mode = ScanImplicitTLS
+	}
+
+	// Offer everything first, so an unreachable server is not mistaken for one rejecting every version
+	var allSuites []string
+	for _, suite := range scanSuites(0) {
+		allSuites = append(allSuites, suite.Name)
+	}
+	if _, err := scanHandshake(ctx, base, tls.VersionTLS10, tls.VersionTLS13, allSuites...); err != nil {
+		scan.Error = err.Error()
+		return scan, nil
+	}
+
+	for _, version := range tlsVersions {
+		result := TLSVersionScan{Version: tls.VersionName(version), Deprecated: version < tls.VersionTLS12}
+		info, err := scanHandshake(ctx, base, version, version, allSuites...)
+		if err != nil {
+			result.Error = err.Error()
+			scan.Versions = append(scan.Versions, result)
+			continue
+		}
+		result.Accepted = true
+		if version == tls.VersionTLS13 {
+			result.CipherSuites = []CipherSuiteScan{newCipherSuiteScan(info.CipherSuite, true)}
+		} else {
+			for _, suite := range scanSuites(version) {
+				_, err := scanHandshake(ctx, base, version, version, suite.Name)
+				result.CipherSuites = append(result.CipherSuites, newCipherSuiteScan(suite.Name, err == nil))
+			}
+		}
+		result.Grade = versionGrade(result)
+		scan.Grade = worstGrade(scan.Grade, result.Grade)
+		scan.Versions = append(scan.Versions, result)
+	}
+	return scan, nil
+}
+
+// scanHandshake dials with only the given versions and cipher suites on offer.
+func scanHandshake(ctx context.Context, base EmailConfig, minVersion, maxVersion uint16, suites ...string) (*TLSInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+	base.TLSMinVersion, base.TLSMaxVersion = tls.VersionName(minVersion), tls.VersionName(maxVersion)
+	base.CipherSuites = suites
+	client := NewClient(&base)
+	if err := client.Dial(ctx); err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.Result().TLS, nil
+}
+
+// scanSuites returns the configurable suites that can be used with the version,
+// or all of them when version is 0.
+func scanSuites(version uint16) []*tls.CipherSuite {
+	var suites []*tls.CipherSuite
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		for _, v := range suite.SupportedVersions {
+			if v == version || (version == 0 && v < tls.VersionTLS13) {
+				suites = append(suites, suite)
+				break
+			}
+		}
+	}
+	return suites
+}
+
+func newCipherSuiteScan(name string, accepted bool) CipherSuiteScan {
+	grade, weakness := cipherSuiteGrade(name)
+	return CipherSuiteScan{Name: name, Accepted: accepted, Grade: grade, Weakness: weakness}
+}
+
+// cipherSuiteGrade fails broken ciphers and marks suites without forward secrecy or with CBC mode as weak.
+func cipherSuiteGrade(name string) (string, string) {
+	switch {
+	case strings.Contains(name, "_RC4_"):
+		return GradeF, "RC4 is broken"
+	case strings.Contains(name, "_3DES_"):
+		return GradeF, "3DES has 64-bit blocks"
+	case strings.HasPrefix(name, "TLS_RSA_"):
+		return GradeB, "no forward secrecy"
+	case strings.Contains(name, "_CBC_"):
+		return GradeB, "CBC mode"
+	}
+	return GradeA, ""
+}
+
+// versionGrade fails deprecated versions, otherwise grades by the worst accepted suite.
+func versionGrade(result TLSVersionScan) string {
+	if result.Deprecated {
+		return GradeF
+	}
+	grade := GradeA
+	for _, suite := range result.CipherSuites {
+		if suite.Accepted {
+			grade = worstGrade(grade, suite.Grade)
+		}
+	}
+	return grade
+}
+
+// worstGrade relies on the grades sorting alphabetically from best to worst.
+func worstGrade(a, b string) string {
+	if a > b {
+		return a
+	}
+	return b
+}
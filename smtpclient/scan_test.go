@@ -0,0 +1,123 @@
+package smtpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acceptedSuites returns the names of the suites the server accepted for the version.
+func acceptedSuites(t *testing.T, scan *TLSScan, version string) []string {
+	t.Helper()
+	for _, v := range scan.Versions {
+		if v.Version == version {
+			var names []string
+			for _, suite := range v.CipherSuites {
+				if suite.Accepted {
+					names = append(names, suite.Name)
+				}
+			}
+			return names
+		}
+	}
+	t.Fatalf("%s was not scanned", version)
+	return nil
+}
+
+func TestScanTLS(t *testing.T) {
+	for _, implicitTLS := range []bool{false, true} {
+		name := map[bool]string{false: "starttls", true: "implicit"}[implicitTLS]
+		t.Run(name, func(t *testing.T) {
+			server := newFakeServer(t, func(s *fakeServer) {
+				s.implicitTLS = implicitTLS
+				s.tlsConfig = newTestTLSConfig(t)
+				s.tlsConfig.MinVersion = tls.VersionTLS12
+				s.tlsConfig.MaxVersion = tls.VersionTLS12
+				s.tlsConfig.CipherSuites = []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA}
+			})
+			emailConfig := server.config()
+			emailConfig.TLS = !implicitTLS
+
+			scan, err := ScanTLS(context.Background(), emailConfig)
+			require.NoError(t, err)
+			assert.Equal(t, name, scan.Mode)
+			assert.Empty(t, scan.Error)
+			assert.Equal(t, GradeB, scan.Grade, "CBC mode is weak")
+
+			var accepted []string
+			for _, v := range scan.Versions {
+				if v.Accepted {
+					accepted = append(accepted, v.Version)
+				}
+			}
+			assert.Equal(t, []string{"TLS 1.2"}, accepted)
+			assert.Equal(t, []string{"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}, acceptedSuites(t, scan, "TLS 1.2"))
+			assert.True(t, scan.Versions[0].Deprecated)
+			assert.NotEmpty(t, scan.Versions[0].Error)
+		})
+	}
+}
+
+func TestScanTLSDeprecatedVersion(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = newTestTLSConfig(t)
+		s.tlsConfig.MinVersion = tls.VersionTLS10
+	})
+	emailConfig := server.config()
+	emailConfig.TLS = true
+
+	scan, err := ScanTLS(context.Background(), emailConfig)
+	require.NoError(t, err)
+	assert.Equal(t, GradeF, scan.Grade)
+	require.Len(t, scan.Versions, 4)
+	for _, v := range scan.Versions {
+		assert.True(t, v.Accepted, v.Version)
+	}
+	assert.Equal(t, "TLS 1.0", scan.Versions[0].Version)
+	assert.True(t, scan.Versions[0].Deprecated)
+	assert.Equal(t, GradeF, scan.Versions[0].Grade)
+	assert.Contains(t, acceptedSuites(t, scan, "TLS 1.0"), "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA")
+	assert.Len(t, acceptedSuites(t, scan, "TLS 1.3"), 1, "only the negotiated TLS 1.3 suite is known")
+	assert.Equal(t, GradeA, scan.Versions[3].Grade)
+}
+
+func TestScanTLSUnreachable(t *testing.T) {
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	emailConfig.TLS = true
+
+	scan, err := ScanTLS(context.Background(), emailConfig)
+	require.NoError(t, err)
+	assert.Contains(t, scan.Error, ErrStartTLSNotSupported.Error())
+	assert.Empty(t, scan.Versions)
+	assert.Empty(t, scan.Grade)
+}
+
+func TestScanTLSNeedsTLS(t *testing.T) {
+	_, err := ScanTLS(context.Background(), &EmailConfig{Host: "127.0.0.1", Port: 25})
+	assert.ErrorIs(t, err, ErrScanNeedsTLS)
+}
+
+func TestCipherSuiteGrade(t *testing.T) {
+	tests := []struct {
+		name     string
+		grade    string
+		weakness string
+	}{
+		{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", GradeA, ""},
+		{"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256", GradeA, ""},
+		{"TLS_AES_256_GCM_SHA384", GradeA, ""},
+		{"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", GradeB, "CBC mode"},
+		{"TLS_RSA_WITH_AES_128_GCM_SHA256", GradeB, "no forward secrecy"},
+		{"TLS_RSA_WITH_3DES_EDE_CBC_SHA", GradeF, "3DES has 64-bit blocks"},
+		{"TLS_ECDHE_RSA_WITH_RC4_128_SHA", GradeF, "RC4 is broken"},
+	}
+	for _, tt := range tests {
+		grade, weakness := cipherSuiteGrade(tt.name)
+		assert.Equal(t, tt.grade, grade, tt.name)
+		assert.Equal(t, tt.weakness, weakness, tt.name)
+	}
+}
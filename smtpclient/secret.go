@@ -0,0 +1,84 @@
+package smtpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Prefixes of the secret references accepted by password.
+const (
+	SecretEnv   = "env:"
+	SecretFile  = "file:"
+	SecretCmd   = "cmd:"
+	SecretPlain = "plain:"
+)
+
+// ResolveSecret returns the secret a reference points to: env:NAME reads an environment
+// variable, file:PATH reads a file, cmd:COMMAND runs a shell command and plain:VALUE is
+// VALUE itself. Other values are returned as they are. Trailing newlines are removed.
+// Errors never contain the secret.
+func ResolveSecret(ctx context.Context, ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, SecretEnv):
+		name := strings.TrimPrefix(ref, SecretEnv)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, SecretFile):
+		value, err := os.ReadFile(strings.TrimPrefix(ref, SecretFile))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(value), "\r\n"), nil
+	case strings.HasPrefix(ref, SecretCmd):
+		return runSecretCommand(ctx, strings.TrimPrefix(ref, SecretCmd))
+	case strings.HasPrefix(ref, SecretPlain):
+		return strings.TrimPrefix(ref, SecretPlain), nil
+	}
+	return ref, nil
+}
+
+// runSecretCommand runs command with the system shell and returns its output.
+func runSecretCommand(ctx context.Context, command string) (string, error) {
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, shell, flag, command)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", fmt.Errorf("%q: %w", command, err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// resolvePassword returns the password from passwordCommand or the password reference.
+func resolvePassword(ctx context.Context, emailConfig *EmailConfig) (string, error) {
+	if emailConfig.PasswordCommand != "" {
+		if emailConfig.Password != "" {
+			return "", errors.New("invalid configuration: set either password or passwordCommand")
+		}
+		password, err := runSecretCommand(ctx, emailConfig.PasswordCommand)
+		if err != nil {
+			return "", fmt.Errorf("passwordCommand: %w", err)
+		}
+		return password, nil
+	}
+	password, err := ResolveSecret(ctx, emailConfig.Password)
+	if err != nil {
+		return "", fmt.Errorf("password: %w", err)
+	}
+	return password, nil
+}
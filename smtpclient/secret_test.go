@@ -0,0 +1,117 @@
+package smtpclient
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecret(t *testing.T) {
+	t.Setenv("GOMTP_TEST_SECRET", "from env")
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("from file\n"), 0600))
+
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{"env:GOMTP_TEST_SECRET", "from env"},
+		{"file:" + path, "from file"},
+		{"cmd:echo from cmd", "from cmd"},
+		{"plain:env:not a reference", "env:not a reference"},
+		{"literal", "literal"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			secret, err := ResolveSecret(context.Background(), tt.ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, secret)
+		})
+	}
+}
+
+func TestResolveSecretErrors(t *testing.T) {
+	tests := []struct {
+		ref string
+		err string
+	}{
+		{"env:GOMTP_TEST_MISSING", "environment variable GOMTP_TEST_MISSING is not set"},
+		{"file:/nonexistent/secret", "open /nonexistent/secret: no such file or directory"},
+		{"cmd:echo not found >&2; exit 3", `"echo not found >&2; exit 3": exit status 3: not found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			_, err := ResolveSecret(context.Background(), tt.ref)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestAuthWithSecretReferences(t *testing.T) {
+	t.Setenv("GOMTP_TEST_SECRET", "secret")
+	tests := map[string]func(*EmailConfig){
+		"env":             func(c *EmailConfig) { c.Password = "env:GOMTP_TEST_SECRET" },
+		"cmd":             func(c *EmailConfig) { c.Password = "cmd:printf secret" },
+		"passwordCommand": func(c *EmailConfig) { c.Password, c.PasswordCommand = "", "echo secret" },
+	}
+	for name, configure := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newAuthServer(t, AuthPlain)
+			emailConfig := authConfig(server, AuthPlain)
+			configure(emailConfig)
+
+			var debug bytes.Buffer
+			client := NewClient(emailConfig)
+			client.Debug = &debug
+			trace := traceSend(t, client, emailConfig)
+			assert.Equal(t, AuthPlain, server.authMechanism())
+			assert.NotContains(t, trace, "secret")
+			assert.NotContains(t, debug.String(), "secret")
+		})
+	}
+}
+
+func TestAuthWithPasswordAndPasswordCommand(t *testing.T) {
+	server := newAuthServer(t, AuthPlain)
+	emailConfig := authConfig(server, AuthPlain)
+	emailConfig.PasswordCommand = "echo secret"
+
+	err := NewClient(emailConfig).Dial(context.Background())
+	assert.EqualError(t, err, "invalid configuration: set either password or passwordCommand")
+}
+
+func TestAuthWithMissingSecret(t *testing.T) {
+	server := newAuthServer(t, AuthPlain)
+	emailConfig := authConfig(server, AuthPlain)
+	emailConfig.Password = "env:GOMTP_TEST_MISSING"
+
+	err := NewClient(emailConfig).Dial(context.Background())
+	assert.EqualError(t, err, "password: environment variable GOMTP_TEST_MISSING is not set")
+	assert.Empty(t, server.received())
+}
+
+func TestNoAuthIgnoresSecret(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	tests := map[string]func(*EmailConfig){
+		"env":             func(c *EmailConfig) { c.Password = "env:GOMTP_TEST_MISSING" },
+		"passwordCommand": func(c *EmailConfig) { c.PasswordCommand = "touch " + marker + "; exit 1" },
+	}
+	for name, configure := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newFakeServer(t, nil)
+			emailConfig := server.config()
+			emailConfig.Auth = AuthNone
+			configure(emailConfig)
+
+			require.NoError(t, send(t, emailConfig))
+			assert.Len(t, server.received(), 1)
+			assert.NoFileExists(t, marker, "the password command is not run")
+		})
+	}
+}
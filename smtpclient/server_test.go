@@ -0,0 +1,272 @@
+package smtpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMessage is a transaction accepted by fakeServer.
+type fakeMessage struct {
+	From       string
+	Recipients []string
+	Data       string
+}
+
+// fakeServer is a minimal in-process SMTP server for exercising the client.
+type fakeServer struct {
+	listener    net.Listener
+	tlsConfig   *tls.Config
+	implicitTLS bool
+	extensions  []string
+	rejectRcpt  map[string]bool
+
+	// AUTH settings; credentials are only checked when authMechanisms is set.
+	authMechanisms []string
+	username       string
+	password       string
+
+	mu       sync.Mutex
+	messages []fakeMessage
+	authUsed string
+}
+
+func newFakeServer(t *testing.T, configure func(s *fakeServer)) *fakeServer {
+	t.Helper()
+	s := &fakeServer{
+		extensions: []string{"8BITMIME", "PIPELINING"},
+		rejectRcpt: map[string]bool{},
+	}
+	if configure != nil {
+		configure(s)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.implicitTLS {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+	s.listener = listener
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// config returns an EmailConfig pointing at the server.
+func (s *fakeServer) config() *EmailConfig {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return &EmailConfig{
+		From: "from@example.com",
+		To:   AddressList{"to@example.com"},
+		Host: addr.IP.String(),
+		Port: addr.Port,
+		SSL:  s.implicitTLS,
+		// The test certificates are self-signed; tests that verify them reset this
+		VerifyCertificate: new(bool),
+	}
+}
+
+func (s *fakeServer) received() []fakeMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeMessage(nil), s.messages...)
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 fake.example.com ESMTP ready")
+
+	var current *fakeMessage
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := append([]string{"fake.example.com"}, s.extensions...)
+			if len(s.authMechanisms) > 0 {
+				lines = append(lines, "AUTH "+strings.Join(s.authMechanisms, " "))
+			}
+			if s.tlsConfig != nil && !s.implicitTLS {
+				if _, ok := conn.(*tls.Conn); !ok {
+					lines = append(lines, "STARTTLS")
+				}
+			}
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				text.PrintfLine("250%s%s", sep, l)
+			}
+		case "STARTTLS":
+			text.PrintfLine("220 2.0.0 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if s.authenticate(text, strings.ToUpper(mechanism), initial) {
+				s.mu.Lock()
+				s.authUsed = strings.ToUpper(mechanism)
+				s.mu.Unlock()
+				text.PrintfLine("235 2.7.0 Authentication successful")
+			} else {
+				text.PrintfLine("535 5.7.8 Authentication credentials invalid")
+			}
+		case "MAIL":
+			current = &fakeMessage{From: trimPath(arg, "FROM:")}
+			text.PrintfLine("250 2.1.0 Ok")
+		case "RCPT":
+			rcpt := trimPath(arg, "TO:")
+			if s.rejectRcpt[rcpt] {
+				text.PrintfLine("550 5.1.1 <%s>: Recipient address rejected", rcpt)
+				continue
+			}
+			current.Recipients = append(current.Recipients, rcpt)
+			text.PrintfLine("250 2.1.5 Ok")
+		case "DATA":
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, *current)
+			s.mu.Unlock()
+			text.PrintfLine("250 2.0.0 Ok: queued as FAKE%d", len(s.received()))
+		case "RSET", "NOOP":
+			text.PrintfLine("250 2.0.0 Ok")
+		case "QUIT":
+			text.PrintfLine("221 2.0.0 Bye")
+			return
+		default:
+			text.PrintfLine("502 5.5.2 Error: command not recognized")
+		}
+	}
+}
+
+// authenticate runs the SASL exchange for one of the supported mechanisms.
+func (s *fakeServer) authenticate(text *textproto.Conn, mechanism, initial string) bool {
+	challenge := func(prompt string) string {
+		text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(prompt)))
+		line, _ := text.ReadLine()
+		decoded, _ := base64.StdEncoding.DecodeString(line)
+		return string(decoded)
+	}
+	decode := func(s string) string {
+		decoded, _ := base64.StdEncoding.DecodeString(s)
+		return string(decoded)
+	}
+
+	switch mechanism {
+	case "PLAIN":
+		response := decode(initial)
+		if initial == "" {
+			response = challenge("")
+		}
+		return response == "\x00"+s.username+"\x00"+s.password
+	case "LOGIN":
+		return challenge("Username:") == s.username && challenge("Password:") == s.password
+	case "CRAM-MD5":
+		nonce := "<1896.697170952@fake.example.com>"
+		username, digest, _ := strings.Cut(challenge(nonce), " ")
+		mac := hmac.New(md5.New, []byte(s.password))
+		mac.Write([]byte(nonce))
+		return username == s.username && digest == hex.EncodeToString(mac.Sum(nil))
+	case "XOAUTH2":
+		ok := decode(initial) == "user="+s.username+"\x01auth=Bearer "+s.password+"\x01\x01"
+		if !ok {
+			challenge(`{"status":"401"}`)
+		}
+		return ok
+	case "OAUTHBEARER":
+		ok := decode(initial) == "n,a="+s.username+",\x01auth=Bearer "+s.password+"\x01\x01"
+		if !ok {
+			challenge(`{"status":"invalid_token"}`)
+		}
+		return ok
+	}
+	return false
+}
+
+func (s *fakeServer) authMechanism() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.authUsed
+}
+
+// trimPath extracts the address from "FROM:<addr> PARAMS" style arguments.
+func trimPath(arg, prefix string) string {
+	arg = strings.TrimSpace(arg[len(prefix):])
+	if end := strings.Index(arg, ">"); strings.HasPrefix(arg, "<") && end > 0 {
+		return arg[1:end]
+	}
+	return arg
+}
+
+// newTestCertificate creates a self-signed certificate for the given hosts.
+func newTestCertificate(t *testing.T, hosts ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newTestTLSConfig returns a server TLS config with a fresh self-signed certificate.
+func newTestTLSConfig(t *testing.T) *tls.Config {
+	return &tls.Config{Certificates: []tls.Certificate{newTestCertificate(t, "127.0.0.1")}}
+}
@@ -0,0 +1,240 @@
+package smtpclient
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// newTLSConfig builds the TLS config used for implicit TLS and STARTTLS. caFile and caDir
+// add certificate authorities to the system pool; clientCert and clientKey enable mutual TLS.
+// The version, cipher suite and curve settings restrict what the handshake may negotiate,
+// and pinSha256 requires a certificate of the server to have one of the pinned keys.
+// Errors are *ConfigError naming the key that failed.
+func newTLSConfig(emailConfig *EmailConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: emailConfig.Host,
+		// The chain is verified in VerifyConnection instead, see verifyChain
+		InsecureSkipVerify: true,
+	}
+	if err := setTLSPolicy(tlsConfig, emailConfig); err != nil {
+		return nil, err
+	}
+
+	if emailConfig.CAFile != "" || emailConfig.CADir != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if emailConfig.CAFile != "" {
+			if err := appendCAFile(pool, emailConfig.CAFile); err != nil {
+				return nil, &ConfigError{Key: "caFile", Err: fmt.Errorf("caFile: %w", err)}
+			}
+		}
+		if emailConfig.CADir != "" {
+			if err := appendCADir(pool, emailConfig.CADir); err != nil {
+				return nil, &ConfigError{Key: "caDir", Err: fmt.Errorf("caDir: %w", err)}
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	// clientKey may be omitted when clientCert holds the key too
+	if emailConfig.ClientKey != "" && emailConfig.ClientCert == "" {
+		return nil, &ConfigError{Key: "clientKey", Err: errors.New("clientKey is set without clientCert")}
+	}
+	if emailConfig.ClientCert != "" {
+		keyFile := emailConfig.ClientKey
+		if keyFile == "" {
+			keyFile = emailConfig.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(emailConfig.ClientCert, keyFile)
+		if err != nil {
+			return nil, &ConfigError{Key: "clientCert", Err: fmt.Errorf("clientCert: %w", err)}
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	pins, err := parsePins(emailConfig.PinSHA256)
+	if err != nil {
+		return nil, &ConfigError{Key: "pinSha256", Err: fmt.Errorf("pinSha256: %w", err)}
+	}
+	// Pins are also checked when verifyCertificate is false, so a pin can replace a CA
+	verify, host, roots := emailConfig.VerifiesCertificate(), emailConfig.Host, tlsConfig.RootCAs
+	tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		if verify {
+			if err := verifyChain(state, host, roots); err != nil {
+				return err
+			}
+		}
+		if len(pins) > 0 {
+			return verifyPins(state, pins)
+		}
+		return nil
+	}
+	return tlsConfig, nil
+}
+
+// SPKIPin returns the base64 SHA-256 hash of the public key of the certificate, as used by pinSha256.
+// It matches the output of:
+//
+//	openssl x509 -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// parsePins accepts base64 hashes, optionally prefixed with sha256// as curl --pinnedpubkey does.
+func parsePins(values []string) (map[string]bool, error) {
+	pins := map[string]bool{}
+	for _, value := range values {
+		pin := strings.TrimPrefix(strings.TrimSpace(value), "sha256//")
+		sum, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("%q is not a base64 SHA-256 hash", value)
+		}
+		pins[pin] = true
+	}
+	return pins, nil
+}
+
+// verifyPins accepts the connection when any certificate of the chain has a pinned key,
+// so pinning an intermediate survives the renewal of the server certificate.
+func verifyPins(state tls.ConnectionState, pins map[string]bool) error {
+	if len(state.PeerCertificates) == 0 {
+		return ErrPinMismatch
+	}
+	for _, cert := range state.PeerCertificates {
+		if pins[SPKIPin(cert)] {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w; the server certificate has %s", ErrPinMismatch, SPKIPin(state.PeerCertificates[0]))
+}
+
+func appendCAFile(pool *x509.CertPool, path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("%s contains no PEM certificates", path)
+	}
+	return nil
+}
+
+// appendCADir adds the certificates of every file in the directory. Files without
+// PEM certificates, such as an OpenSSL hash link index, are skipped.
+func appendCADir(pool *x509.CertPool, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		pem, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		found = pool.AppendCertsFromPEM(pem) || found
+	}
+	if !found {
+		return fmt.Errorf("%s contains no PEM certificates", dir)
+	}
+	return nil
+}
+
+var tlsVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+var curves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P-256":  tls.CurveP256,
+	"P-384":  tls.CurveP384,
+	"P-521":  tls.CurveP521,
+}
+
+// setTLSPolicy applies tlsMinVersion, tlsMaxVersion, cipherSuites and curvePreferences.
+func setTLSPolicy(tlsConfig *tls.Config, emailConfig *EmailConfig) error {
+	var err error
+	if emailConfig.TLSMinVersion != "" {
+		if tlsConfig.MinVersion, err = parseTLSVersion(emailConfig.TLSMinVersion); err != nil {
+			return &ConfigError{Key: "tlsMinVersion", Err: fmt.Errorf("tlsMinVersion: %w", err)}
+		}
+	}
+	if emailConfig.TLSMaxVersion != "" {
+		if tlsConfig.MaxVersion, err = parseTLSVersion(emailConfig.TLSMaxVersion); err != nil {
+			return &ConfigError{Key: "tlsMaxVersion", Err: fmt.Errorf("tlsMaxVersion: %w", err)}
+		}
+	}
+	// crypto/tls offers TLS 1.2 and up by default, so a lower maximum alone would allow no version
+	if tlsConfig.MinVersion == 0 && tlsConfig.MaxVersion != 0 && tlsConfig.MaxVersion < tls.VersionTLS12 {
+		tlsConfig.MinVersion = tls.VersionTLS10
+	}
+	if tlsConfig.MinVersion != 0 && tlsConfig.MaxVersion != 0 && tlsConfig.MaxVersion < tlsConfig.MinVersion {
+		return &ConfigError{Key: "tlsMaxVersion", Err: fmt.Errorf("tlsMaxVersion %s is lower than tlsMinVersion %s",
+			tls.VersionName(tlsConfig.MaxVersion), tls.VersionName(tlsConfig.MinVersion))}
+	}
+	for _, name := range emailConfig.CipherSuites {
+		id, err := parseCipherSuite(name)
+		if err != nil {
+			return &ConfigError{Key: "cipherSuites", Err: fmt.Errorf("cipherSuites: %w", err)}
+		}
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+	}
+	for _, name := range emailConfig.CurvePreferences {
+		id, err := parseCurve(name)
+		if err != nil {
+			return &ConfigError{Key: "curvePreferences", Err: fmt.Errorf("curvePreferences: %w", err)}
+		}
+		tlsConfig.CurvePreferences = append(tlsConfig.CurvePreferences, id)
+	}
+	return nil
+}
+
+// parseTLSVersion accepts "1.2" as well as the names printed by gomtp, such as "TLS 1.2".
+func parseTLSVersion(name string) (uint16, error) {
+	number := strings.TrimSpace(name)
+	if len(number) > 3 && strings.EqualFold(number[:3], "TLS") {
+		number = strings.TrimSpace(number[3:])
+	}
+	for _, version := range tlsVersions {
+		if "TLS "+number == tls.VersionName(version) {
+			return version, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown TLS version %q; use 1.0, 1.1, 1.2 or 1.3", name)
+}
+
+// parseCipherSuite looks up a suite by its IANA name, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+// Insecure suites are allowed so that a server can be checked for rejecting them.
+func parseCipherSuite(name string) (uint16, error) {
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if !strings.EqualFold(suite.Name, strings.TrimSpace(name)) {
+			continue
+		}
+		// crypto/tls always offers its own TLS 1.3 suites
+		if len(suite.SupportedVersions) == 1 && suite.SupportedVersions[0] == tls.VersionTLS13 {
+			return 0, fmt.Errorf("%s is a TLS 1.3 suite, which cannot be configured", suite.Name)
+		}
+		return suite.ID, nil
+	}
+	return 0, fmt.Errorf("unknown cipher suite %q", name)
+}
+
+func parseCurve(name string) (tls.CurveID, error) {
+	for curveName, id := range curves {
+		if strings.EqualFold(curveName, strings.TrimSpace(name)) || strings.EqualFold(id.String(), strings.TrimSpace(name)) {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown curve %q; use X25519, P-256, P-384 or P-521", name)
+}
@@ -0,0 +1,352 @@
+package smtpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA is a certificate authority created for a test.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue creates a certificate signed by the CA for the given hosts.
+func (ca *testCA) issue(t *testing.T, usage x509.ExtKeyUsage, hosts ...string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: hosts[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// writePEM writes the blocks to a file in dir and returns its path.
+func writePEM(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	var content []byte
+	for _, block := range blocks {
+		content = append(content, pem.EncodeToMemory(block)...)
+	}
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	return path
+}
+
+func certBlock(der []byte) *pem.Block {
+	return &pem.Block{Type: "CERTIFICATE", Bytes: der}
+}
+
+func keyBlock(t *testing.T, cert tls.Certificate) *pem.Block {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+	return &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+}
+
+// newPrivateCAServer starts a server whose certificate is signed by a private CA.
+func newPrivateCAServer(t *testing.T, implicitTLS bool, configure func(s *fakeServer)) (*fakeServer, *testCA) {
+	ca := newTestCA(t, "Test Private CA")
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.implicitTLS = implicitTLS
+		s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{ca.issue(t, x509.ExtKeyUsageServerAuth, "127.0.0.1")}}
+		if configure != nil {
+			configure(s)
+		}
+	})
+	return server, ca
+}
+
+func TestCAFile(t *testing.T) {
+	for _, implicitTLS := range []bool{false, true} {
+		name := map[bool]string{false: "starttls", true: "implicit"}[implicitTLS]
+		t.Run(name, func(t *testing.T) {
+			server, ca := newPrivateCAServer(t, implicitTLS, nil)
+			emailConfig := server.config()
+			emailConfig.TLS = !implicitTLS
+			emailConfig.VerifyCertificate = nil
+
+			err := send(t, emailConfig)
+			assert.ErrorContains(t, err, "certificate signed by unknown authority")
+
+			emailConfig.CAFile = writePEM(t, t.TempDir(), "ca.pem", certBlock(ca.cert.Raw))
+			require.NoError(t, send(t, emailConfig))
+			assert.Len(t, server.received(), 1)
+		})
+	}
+}
+
+func TestCADir(t *testing.T) {
+	server, ca := newPrivateCAServer(t, false, nil)
+	dir := t.TempDir()
+	writePEM(t, dir, "other.pem", certBlock(newTestCA(t, "Other CA").cert.Raw))
+	writePEM(t, dir, "private.crt", certBlock(ca.cert.Raw))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("not a certificate"), 0600))
+
+	emailConfig := server.config()
+	emailConfig.TLS = true
+	emailConfig.VerifyCertificate = nil
+	emailConfig.CADir = dir
+	require.NoError(t, send(t, emailConfig))
+}
+
+func TestClientCertificate(t *testing.T) {
+	clientCA := newTestCA(t, "Test Client CA")
+	for _, implicitTLS := range []bool{false, true} {
+		name := map[bool]string{false: "starttls", true: "implicit"}[implicitTLS]
+		t.Run(name, func(t *testing.T) {
+			server, ca := newPrivateCAServer(t, implicitTLS, func(s *fakeServer) {
+				s.tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+				s.tlsConfig.ClientCAs = clientCA.pool()
+			})
+			dir := t.TempDir()
+			emailConfig := server.config()
+			emailConfig.TLS = !implicitTLS
+			emailConfig.VerifyCertificate = nil
+			emailConfig.CAFile = writePEM(t, dir, "ca.pem", certBlock(ca.cert.Raw))
+
+			assert.Error(t, send(t, emailConfig), "the server requires a client certificate")
+
+			cert := clientCA.issue(t, x509.ExtKeyUsageClientAuth, "client.example.com")
+			emailConfig.ClientCert = writePEM(t, dir, "client.crt", certBlock(cert.Certificate[0]))
+			emailConfig.ClientKey = writePEM(t, dir, "client.key", keyBlock(t, cert))
+			require.NoError(t, send(t, emailConfig))
+
+			emailConfig.ClientCert = writePEM(t, dir, "client.pem", certBlock(cert.Certificate[0]), keyBlock(t, cert))
+			emailConfig.ClientKey = ""
+			require.NoError(t, send(t, emailConfig), "clientCert may hold the key too")
+			assert.Len(t, server.received(), 2)
+		})
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := writePEM(t, dir, "empty.pem")
+	cert := newTestCA(t, "Test CA").issue(t, x509.ExtKeyUsageClientAuth, "client.example.com")
+	certOnly := writePEM(t, dir, "cert.pem", certBlock(cert.Certificate[0]))
+
+	tests := []struct {
+		name   string
+		config EmailConfig
+		key    string
+		err    string
+	}{
+		{"missing caFile", EmailConfig{CAFile: filepath.Join(dir, "missing.pem")}, "caFile", "caFile: open " + filepath.Join(dir, "missing.pem") + ": no such file or directory"},
+		{"caFile without certificates", EmailConfig{CAFile: empty}, "caFile", "caFile: " + empty + " contains no PEM certificates"},
+		{"caDir without certificates", EmailConfig{CADir: t.TempDir()}, "caDir", "contains no PEM certificates"},
+		{"clientKey without clientCert", EmailConfig{ClientKey: certOnly}, "clientKey", "clientKey is set without clientCert"},
+		{"clientCert without key", EmailConfig{ClientCert: certOnly}, "clientCert", "clientCert: tls: found a certificate rather than a key"},
+		{"unknown version", EmailConfig{TLSMinVersion: "1.4"}, "tlsMinVersion", `tlsMinVersion: unknown TLS version "1.4"; use 1.0, 1.1, 1.2 or 1.3`},
+		{"max below min", EmailConfig{TLSMinVersion: "1.2", TLSMaxVersion: "TLS 1.1"}, "tlsMaxVersion", "tlsMaxVersion TLS 1.1 is lower than tlsMinVersion TLS 1.2"},
+		{"unknown cipher suite", EmailConfig{CipherSuites: []string{"TLS_NULL"}}, "cipherSuites", `cipherSuites: unknown cipher suite "TLS_NULL"`},
+		{"TLS 1.3 cipher suite", EmailConfig{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}}, "cipherSuites", "cipherSuites: TLS_AES_128_GCM_SHA256 is a TLS 1.3 suite, which cannot be configured"},
+		{"invalid pin", EmailConfig{PinSHA256: []string{"abc"}}, "pinSha256", `pinSha256: "abc" is not a base64 SHA-256 hash`},
+		{"unknown curve", EmailConfig{CurvePreferences: []string{"P-192"}}, "curvePreferences", `curvePreferences: unknown curve "P-192"; use X25519, P-256, P-384 or P-521`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emailConfig := tt.config
+			emailConfig.Host, emailConfig.Port, emailConfig.From = "smtp.example.com", 465, "from@example.com"
+			_, err := newTLSConfig(&emailConfig)
+			assert.ErrorContains(t, err, tt.err)
+
+			problems := ValidateConfig(&emailConfig)
+			if assert.Len(t, problems, 1) {
+				assert.Equal(t, tt.key, problems[0].Key)
+			}
+		})
+	}
+}
+
+// dial connects to the server with the config and returns the connected client.
+func dial(t *testing.T, emailConfig *EmailConfig) (*Client, error) {
+	t.Helper()
+	client := NewClient(emailConfig)
+	if err := client.Dial(context.Background()); err != nil {
+		return client, err
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, nil
+}
+
+func TestTLSVersionPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		server    *tls.Config
+		min, max  string
+		version   string
+		errorText string
+	}{
+		{name: "server rejects TLS 1.1", server: &tls.Config{MinVersion: tls.VersionTLS12}, max: "1.1", errorText: "protocol version"},
+		{name: "client offers TLS 1.1", server: &tls.Config{MinVersion: tls.VersionTLS10}, max: "1.1", version: "TLS 1.1"},
+		{name: "client requires TLS 1.3", server: &tls.Config{MaxVersion: tls.VersionTLS12}, min: "1.3", errorText: "protocol version"},
+		{name: "client caps at TLS 1.2", server: &tls.Config{}, max: "TLS 1.2", version: "TLS 1.2"},
+		{name: "both allow TLS 1.3", server: &tls.Config{}, min: "1.2", version: "TLS 1.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(s *fakeServer) {
+				s.tlsConfig = tt.server
+				s.tlsConfig.Certificates = []tls.Certificate{newTestCertificate(t, "127.0.0.1")}
+			})
+			emailConfig := server.config()
+			emailConfig.TLS = true
+			emailConfig.TLSMinVersion, emailConfig.TLSMaxVersion = tt.min, tt.max
+
+			client, err := dial(t, emailConfig)
+			if tt.errorText != "" {
+				assert.ErrorContains(t, err, tt.errorText)
+				assert.Equal(t, PhaseStartTLS, client.Result().Phase)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, client.Result().TLS.Version)
+		})
+	}
+}
+
+func TestCipherSuitePolicy(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.implicitTLS = true
+		s.tlsConfig = newTestTLSConfig(t)
+		s.tlsConfig.MaxVersion = tls.VersionTLS12
+		s.tlsConfig.CipherSuites = []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}
+	})
+	emailConfig := server.config()
+
+	emailConfig.CipherSuites = []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"}
+	_, err := dial(t, emailConfig)
+	assert.ErrorContains(t, err, "handshake failure")
+
+	emailConfig.CipherSuites = []string{"tls_ecdhe_ecdsa_with_aes_128_gcm_sha256"}
+	var debug bytes.Buffer
+	client := NewClient(emailConfig)
+	client.Debug = &debug
+	require.NoError(t, client.Dial(context.Background()))
+	defer client.Close()
+	assert.Equal(t, "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", client.Result().TLS.CipherSuite)
+	assert.Contains(t, debug.String(), `negotiated_tls=version:"TLS 1.2" cipher_suite:"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256" `)
+}
+
+func TestCurvePolicy(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = newTestTLSConfig(t)
+		s.tlsConfig.CurvePreferences = []tls.CurveID{tls.CurveP384}
+	})
+	emailConfig := server.config()
+	emailConfig.TLS = true
+
+	emailConfig.CurvePreferences = []string{"X25519"}
+	_, err := dial(t, emailConfig)
+	assert.ErrorContains(t, err, "handshake failure")
+
+	emailConfig.CurvePreferences = []string{"X25519", "P-384"}
+	_, err = dial(t, emailConfig)
+	assert.NoError(t, err)
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	for name, want := range map[string]uint16{"1.0": tls.VersionTLS10, "1.1": tls.VersionTLS11, "TLS 1.2": tls.VersionTLS12, "tls1.3": tls.VersionTLS13} {
+		version, err := parseTLSVersion(name)
+		assert.NoError(t, err)
+		assert.Equal(t, want, version, name)
+	}
+	_, err := parseTLSVersion("TLS")
+	assert.Error(t, err)
+}
+
+func TestPinSHA256(t *testing.T) {
+	ca := newTestCA(t, "Test Pinned CA")
+	leaf := ca.issue(t, x509.ExtKeyUsageServerAuth, "127.0.0.1")
+	leaf.Certificate = append(leaf.Certificate, ca.cert.Raw)
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{leaf}}
+	})
+	leafCert, err := x509.ParseCertificate(leaf.Certificate[0])
+	require.NoError(t, err)
+	leafPin, caPin := SPKIPin(leafCert), SPKIPin(ca.cert)
+	otherPin := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+
+	tests := []struct {
+		name string
+		pins []string
+		err  error
+	}{
+		{"server certificate", []string{otherPin, leafPin}, nil},
+		{"issuer", []string{"sha256//" + caPin}, nil},
+		{"mismatch", []string{otherPin}, ErrPinMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emailConfig := server.config()
+			emailConfig.TLS = true
+			emailConfig.PinSHA256 = tt.pins
+
+			_, err := dial(t, emailConfig)
+			if tt.err == nil {
+				assert.NoError(t, err, "a pin works without verifyCertificate")
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+			assert.ErrorContains(t, err, "the server certificate has "+leafPin)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package smtpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// tracer writes the SMTP conversation to w, one timestamped line per command or reply.
+type tracer struct {
+	w          io.Writer
+	redactBody bool
+
+	// Set by the session while credentials or message data are on the wire.
+	inAuth   bool
+	inBody   bool
+	bodySize int
+
+	// Partial lines not yet terminated by LF.
+	client, server []byte
+}
+
+// traceConn logs the plaintext passing through the wrapped connection.
+type traceConn struct {
+	net.Conn
+	tracer *tracer
+}
+
+func (c *traceConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.tracer.server = c.tracer.feed(c.tracer.server, p[:n], "S")
+	return n, err
+}
+
+func (c *traceConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.tracer.client = c.tracer.feed(c.tracer.client, p[:n], "C")
+	return n, err
+}
+
+// wrap returns conn logging through t, or conn itself when tracing is off.
+func (t *tracer) wrap(conn net.Conn) net.Conn {
+	if t == nil {
+		return conn
+	}
+	t.client, t.server = nil, nil
+	return &traceConn{Conn: conn, tracer: t}
+}
+
+// feed appends data to the partial line in buf and logs every completed line.
+func (t *tracer) feed(buf, data []byte, prefix string) []byte {
+	buf = append(buf, data...)
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			return buf
+		}
+		t.line(prefix, strings.TrimSuffix(string(buf[:i]), "\r"))
+		buf = buf[i+1:]
+	}
+}
+
+func (t *tracer) line(prefix, line string) {
+	if prefix == "C" {
+		switch {
+		case t.inBody && t.redactBody:
+			t.bodySize += len(line) + 2
+			return
+		case t.inAuth:
+			line = redactAuth(line)
+		}
+	}
+	t.printf("%s: %s", prefix, line)
+}
+
+// event logs something that is not part of the conversation, such as a completed TLS handshake.
+func (t *tracer) event(format string, args ...any) {
+	if t != nil {
+		t.printf("* "+format, args...)
+	}
+}
+
+// auth marks the start and end of the SASL exchange, whose client lines are redacted.
+func (t *tracer) auth(on bool) {
+	if t != nil {
+		t.inAuth = on
+	}
+}
+
+// body marks the start and end of the message data.
+func (t *tracer) body(on bool) {
+	if t == nil {
+		return
+	}
+	t.inBody = on
+	if !on && t.redactBody {
+		t.printf("C: <%d bytes of message data redacted>", t.bodySize)
+		t.bodySize = 0
+	}
+}
+
+func (t *tracer) printf(format string, args ...any) {
+	fmt.Fprintf(t.w, "%s %s\n", time.Now().Format("15:04:05.000"), fmt.Sprintf(format, args...))
+}
+
+// redactAuth hides the SASL payload of a client line sent during AUTH.
+func redactAuth(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) > 0 && strings.EqualFold(fields[0], "AUTH") {
+		if len(fields) > 2 {
+			return fields[0] + " " + fields[1] + " <redacted>"
+		}
+		return line
+	}
+	return "<redacted>"
+}
@@ -0,0 +1,81 @@
+package smtpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func traceSend(t *testing.T, client *Client, emailConfig *EmailConfig) string {
+	t.Helper()
+	var trace bytes.Buffer
+	client.Trace = &trace
+	ctx := context.Background()
+	require.NoError(t, client.Dial(ctx))
+	require.NoError(t, client.Send(ctx, newMessage(t, emailConfig)))
+	require.NoError(t, client.Close())
+	return trace.String()
+}
+
+func TestTrace(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) {
+		s.tlsConfig = newTestTLSConfig(t)
+	})
+	emailConfig := server.config()
+	emailConfig.TLS = true
+	emailConfig.Body = "Hello from the trace test."
+
+	trace := traceSend(t, NewClient(emailConfig), emailConfig)
+
+	assert.Regexp(t, regexp.MustCompile(`(?m)^\d{2}:\d{2}:\d{2}\.\d{3} S: 220 fake\.example\.com ESMTP ready$`), trace)
+	assert.Contains(t, trace, "C: EHLO 127.0.0.1\n")
+	assert.Contains(t, trace, "C: STARTTLS\n")
+	assert.Contains(t, trace, "* TLS established: TLS 1.3")
+	assert.Contains(t, trace, "C: MAIL FROM:<from@example.com> BODY=8BITMIME\n", "commands after STARTTLS are logged in plaintext")
+	assert.Contains(t, trace, "C: RCPT TO:<to@example.com>\n")
+	assert.Contains(t, trace, "C: Hello from the trace test.\n")
+	assert.Contains(t, trace, "S: 250 2.0.0 Ok: queued as FAKE1\n")
+	assert.Contains(t, trace, "C: QUIT\n")
+}
+
+func TestTraceRedactsAuth(t *testing.T) {
+	for _, mechanism := range []string{AuthPlain, AuthLogin} {
+		t.Run(mechanism, func(t *testing.T) {
+			server := newAuthServer(t, mechanism)
+			emailConfig := authConfig(server, mechanism)
+
+			trace := traceSend(t, NewClient(emailConfig), emailConfig)
+
+			assert.Contains(t, trace, "S: 235 2.7.0 Authentication successful\n")
+			assert.NotContains(t, trace, "secret")
+			assert.NotContains(t, trace, base64.StdEncoding.EncodeToString([]byte("secret")))
+			assert.NotContains(t, trace, base64.StdEncoding.EncodeToString([]byte("\x00user@example.com\x00secret")))
+			assert.Contains(t, trace, "C: MAIL FROM:", "redaction ends with the exchange")
+		})
+	}
+}
+
+func TestTraceRedactsBody(t *testing.T) {
+	server := newFakeServer(t, nil)
+	emailConfig := server.config()
+	emailConfig.Body = "Hello from the trace test."
+
+	client := NewClient(emailConfig)
+	client.TraceRedactBody = true
+	trace := traceSend(t, client, emailConfig)
+
+	assert.NotContains(t, trace, "Hello from the trace test.")
+	assert.NotContains(t, trace, "Subject:")
+	assert.Regexp(t, `C: <\d+ bytes of message data redacted>\n.* S: 250 2\.0\.0 Ok: queued as FAKE1\n`, trace)
+}
+
+func TestRedactAuth(t *testing.T) {
+	assert.Equal(t, "AUTH PLAIN <redacted>", redactAuth("AUTH PLAIN AHVzZXIAc2VjcmV0"))
+	assert.Equal(t, "AUTH LOGIN", redactAuth("AUTH LOGIN"))
+	assert.Equal(t, "<redacted>", redactAuth("dXNlcg=="))
+}
@@ -0,0 +1,88 @@
+package smtpclient
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ConfigError is a problem with one key of the configuration.
+type ConfigError struct {
+	// Key is the YAML key, e.g. "port" or "oauth2.tokenUrl".
+	Key string
+	Err error
+}
+
+func (e *ConfigError) Error() string { return e.Err.Error() }
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// ValidateConfig checks the config for every problem that would fail a send,
+// without connecting. It returns nil when the config is valid.
+func ValidateConfig(emailConfig *EmailConfig) []*ConfigError {
+	var problems []*ConfigError
+	add := func(key string, err error) {
+		problems = append(problems, &ConfigError{Key: key, Err: err})
+	}
+
+	if emailConfig.Host == "" {
+		add("host", errors.New("host is not set"))
+	}
+	if emailConfig.Port == 0 {
+		add("port", errors.New("port is not set"))
+	} else if emailConfig.Port < 1 || emailConfig.Port > 65535 {
+		add("port", fmt.Errorf("port %d is out of range 1-65535", emailConfig.Port))
+	}
+	if emailConfig.SSL && emailConfig.TLS {
+		add("tls", errors.New("ssl and tls are both enabled; use ssl for implicit TLS or tls for STARTTLS"))
+	}
+
+	mechanism, err := authMechanism(emailConfig.Auth)
+	if err != nil {
+		add("auth", unsupportedAuth(emailConfig.Auth))
+	} else if mechanism != "" && emailConfig.Username == "" {
+		add("auth", fmt.Errorf("auth %s needs a username", mechanism))
+	}
+	if emailConfig.Password != "" && emailConfig.PasswordCommand != "" {
+		add("passwordCommand", errors.New("set either password or passwordCommand"))
+	}
+	if emailConfig.OAuth2.Enabled() && emailConfig.OAuth2.TokenURL == "" {
+		add("oauth2.refreshToken", errors.New("oauth2.tokenUrl is required with oauth2.refreshToken"))
+	}
+
+	// MAIL FROM falls back to from, so it may only be left out when envelopeFrom is set
+	if emailConfig.From == "" && emailConfig.EnvelopeFrom == "" {
+		add("from", errors.New("from is not set"))
+	}
+	for _, field := range []struct {
+		key       string
+		addresses []string
+	}{
+		{"from", []string{emailConfig.From}},
+		{"sender", []string{emailConfig.Sender}},
+		{"replyTo", emailConfig.ReplyTo},
+		{"to", emailConfig.To},
+		{"cc", emailConfig.CcList},
+		{"bcc", emailConfig.BccList},
+	} {
+		for _, address := range field.addresses {
+			if _, err := parseAddresses(field.key, []string{address}); err != nil {
+				add(field.key, err)
+			}
+		}
+	}
+	if emailConfig.EnvelopeFrom != "" && emailConfig.EnvelopeFrom != NullReversePath {
+		if _, err := parseAddress("envelopeFrom", emailConfig.EnvelopeFrom); err != nil {
+			add("envelopeFrom", err)
+		}
+	}
+	if err := validateHeaders(emailConfig); err != nil {
+		add("headers", err)
+	}
+	if _, err := newTLSConfig(emailConfig); err != nil {
+		var configErr *ConfigError
+		if errors.As(err, &configErr) {
+			problems = append(problems, configErr)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,67 @@
+package smtpclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifiesCertificate(t *testing.T) {
+	var emailConfig EmailConfig
+	assert.True(t, emailConfig.VerifiesCertificate(), "the zero value verifies")
+	emailConfig.VerifyCertificate = new(bool)
+	assert.False(t, emailConfig.VerifiesCertificate())
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := func() *EmailConfig {
+		return &EmailConfig{Host: "smtp.example.com", Port: 587, TLS: true, Auth: AuthPlain, Username: "user", From: "from@example.com", To: AddressList{"to@example.com"}}
+	}
+	assert.Empty(t, ValidateConfig(valid()))
+
+	tests := []struct {
+		name    string
+		change  func(*EmailConfig)
+		key     string
+		message string
+	}{
+		{"missing host", func(c *EmailConfig) { c.Host = "" }, "host", "host is not set"},
+		{"missing port", func(c *EmailConfig) { c.Port = 0 }, "port", "port is not set"},
+		{"port out of range", func(c *EmailConfig) { c.Port = 70000 }, "port", "port 70000 is out of range 1-65535"},
+		{"ssl and tls", func(c *EmailConfig) { c.SSL = true }, "tls", "ssl and tls are both enabled; use ssl for implicit TLS or tls for STARTTLS"},
+		{"unsupported auth", func(c *EmailConfig) { c.Auth = "NTLM" }, "auth", `unsupported auth "NTLM"; choose one of NO | PLAIN | LOGIN | CRAM-MD5 | XOAUTH2 | OAUTHBEARER | AUTO`},
+		{"auth without username", func(c *EmailConfig) { c.Username = "" }, "auth", "auth PLAIN needs a username"},
+		{"password and passwordCommand", func(c *EmailConfig) { c.Password, c.PasswordCommand = "a", "b" }, "passwordCommand", "set either password or passwordCommand"},
+		{"refresh token without url", func(c *EmailConfig) { c.OAuth2.RefreshToken = "token" }, "oauth2.refreshToken", "oauth2.tokenUrl is required with oauth2.refreshToken"},
+		{"missing from", func(c *EmailConfig) { c.From = "" }, "from", "from is not set"},
+		{"invalid from", func(c *EmailConfig) { c.From = "from" }, "from", `invalid from address "from": mail: missing '@' or angle-addr`},
+		{"invalid cc", func(c *EmailConfig) { c.CcList = []string{"ok@example.com", "cc@"} }, "cc", `invalid cc address "cc@": mail: missing '@' or angle-addr`},
+		{"invalid envelopeFrom", func(c *EmailConfig) { c.EnvelopeFrom = "bounce" }, "envelopeFrom", `invalid envelopeFrom address "bounce": mail: missing '@' or angle-addr`},
+		{"structural header", func(c *EmailConfig) { c.Headers = map[string]string{"To": "x@example.com"} }, "headers", `invalid header "To": it is set by gomtp; enable overrideHeaders to replace it`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emailConfig := valid()
+			tt.change(emailConfig)
+			problems := ValidateConfig(emailConfig)
+			if assert.Len(t, problems, 1) {
+				assert.Equal(t, tt.key, problems[0].Key)
+				assert.EqualError(t, problems[0], tt.message)
+			}
+		})
+	}
+}
+
+func TestValidateConfigReportsEveryProblem(t *testing.T) {
+	problems := ValidateConfig(&EmailConfig{SSL: true, TLS: true, To: AddressList{"a@", "b@"}})
+	var keys []string
+	for _, problem := range problems {
+		keys = append(keys, problem.Key)
+	}
+	assert.Equal(t, []string{"host", "port", "tls", "from", "to", "to"}, keys)
+}
+
+func TestValidateConfigWithEnvelopeFromOnly(t *testing.T) {
+	emailConfig := &EmailConfig{Host: "smtp.example.com", Port: 25, EnvelopeFrom: "bounce@example.com", To: AddressList{"to@example.com"}}
+	assert.Empty(t, ValidateConfig(emailConfig))
+}